@@ -14,33 +14,17 @@ func main() {
 	// Create a new background context.
 	ctx := context.Background()
 
-	// Create a new authentication client.
-	ath := auth.NewClient()
-
-	// Attempt to log in using the environment's WME_USERNAME and WME_PASSWORD.
-	lgn, err := ath.Login(ctx, &auth.LoginRequest{
+	// Create a new token source that will log in using the environment's WME_USERNAME and WME_PASSWORD.
+	// Stream can stay open for longer than the access token lives, so the token source will keep it fresh for us.
+	tks := auth.NewTokenSource(auth.NewClient(), &auth.LoginRequest{
 		Username: os.Getenv("WME_USERNAME"),
 		Password: os.Getenv("WME_PASSWORD"),
 	})
 
-	if err != nil {
-		log.Panic(err)
-	}
-
-	// Make sure to revoke the token once we're done.
-	defer func() {
-		err := ath.RevokeToken(ctx, &auth.RevokeTokenRequest{
-			RefreshToken: lgn.RefreshToken,
-		})
-
-		if err != nil {
-			log.Println(err)
-		}
-	}()
-
-	// Create a new API client and set the access token to the one we just obtained.
-	clt := api.NewClient()
-	clt.SetAccessToken(lgn.AccessToken)
+	// Create a new API client and set the token source.
+	clt := api.NewClient(func(clt *api.Client) {
+		clt.TokenSource = tks
+	})
 
 	// Create a new API request for articles, specifying the fields we want to retrieve and the filters to apply.
 	arq := &api.Request{
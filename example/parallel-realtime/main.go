@@ -8,8 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/protsack-stephan/wme/pkg/api"
 	"github.com/protsack-stephan/wme/pkg/auth"
-	"github.com/protsack-stephan/wme/pkg/realtime"
 	"github.com/protsack-stephan/wme/schema/v2"
 )
 
@@ -38,8 +38,8 @@ func main() {
 		}
 	}()
 
-	rlt := realtime.NewClient()
-	rlt.SetAccessToken(lgn.AccessToken)
+	clt := api.NewClient()
+	clt.SetAccessToken(lgn.AccessToken)
 
 	hdl := func(art *schema.Article) error {
 		dta, _ := json.Marshal(art)
@@ -52,25 +52,25 @@ func main() {
 
 	go func() {
 		defer wg.Done()
-		arq := &realtime.ArticlesRequest{
+		arq := &api.Request{
 			Fields: []string{"name", "event.*"},
 			Parts:  []int{2, 1}, // For partitions 0 through 49. This will connect to partitions 5 through 14
 		}
 
-		if err := rlt.Articles(ctx, arq, hdl); err != nil {
+		if err := clt.StreamArticles(ctx, arq, hdl); err != nil {
 			log.Panic(err)
 		}
 	}()
 
 	go func() {
 		defer wg.Done()
-		arq := &realtime.ArticlesRequest{
+		arq := &api.Request{
 			Fields: []string{"name", "event.*"},
 			Parts:  []int{0, 1}, // Will connect to partitions 0 through 9
 			// API will pick offsets for relevant partitions (0 through 9); for other partitions API will return messages from the earliest available
 			// API will ignore irrelevant partitions in offset.
 			Offsets: map[int]int64{19: 1, 22: 1, 15: 1, 21: 1, 33: 1, 38: 1, 44: 1, 3: 1, 5: 1, 7: 1, 34: 1, 13: 1, 24: 1, 30: 1, 20: 1, 25: 1, 39: 1, 42: 1, 145: 1, 4: 1, 14: 1, 18: 1, 146: 1, 47: 1, 29: 1, 40: 1, 41: 1, 43: 1, 149: 1, 9: 1, 27: 1, 28: 1, 10: 1, 31: 1, 36: 1, 37: 1, 0: 1, 1: 1, 2: 1},
-			Filters: []realtime.Filter{
+			Filters: []*api.Filter{
 				{
 					Field: "is_part_of.identifier",
 					Value: "enwiki",
@@ -86,14 +86,14 @@ func main() {
 			},
 		}
 
-		if err := rlt.Articles(ctx, arq, hdl); err != nil {
+		if err := clt.StreamArticles(ctx, arq, hdl); err != nil {
 			log.Panic(err)
 		}
 	}()
 
 	go func() {
 		defer wg.Done()
-		arq := &realtime.ArticlesRequest{
+		arq := &api.Request{
 			Fields: []string{"name", "event.*"},
 			Parts:  []int{0, 9}, // Will connect to partitions 0,1,2,3,4,45,46,47,48,49
 			// Time-Offsets for partitions 0,1,2,3 and 46; for other partitions consume from earliest
@@ -106,7 +106,7 @@ func main() {
 				46:  time.Now().UTC().Add(-10 * time.Hour),
 				146: time.Now().UTC().Add(-10 * time.Hour),
 			},
-			Filters: []realtime.Filter{
+			Filters: []*api.Filter{
 				{
 					Field: "is_part_of.identifier",
 					Value: "eswiki",
@@ -118,7 +118,7 @@ func main() {
 			},
 		}
 
-		if err := rlt.Articles(ctx, arq, hdl); err != nil {
+		if err := clt.StreamArticles(ctx, arq, hdl); err != nil {
 			log.Panic(err)
 		}
 	}()
@@ -0,0 +1,45 @@
+// Package atomicfile writes files so that readers see either the old or the new content, never a partial one.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes the data to a temporary file in the same directory and renames it over the path.
+// The temporary file is synced before the rename, so a crash doesn't leave an empty or truncated file behind.
+// Missing directories are created and readable only by the owner.
+func WriteFile(pth string, dta []byte, prm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(pth), filepath.Base(pth))
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(dta); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(prm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), pth)
+}
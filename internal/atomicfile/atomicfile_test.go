@@ -0,0 +1,59 @@
+package atomicfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protsack-stephan/wme/internal/atomicfile"
+	"github.com/stretchr/testify/suite"
+)
+
+type writeFileTestSuite struct {
+	suite.Suite
+	pth string
+	old []byte
+	dta []byte
+	prm os.FileMode
+}
+
+func (s *writeFileTestSuite) SetupTest() {
+	s.pth = filepath.Join(s.T().TempDir(), "data", "file.json")
+
+	if s.old != nil {
+		s.Require().NoError(atomicfile.WriteFile(s.pth, s.old, 0644))
+	}
+}
+
+func (s *writeFileTestSuite) TestWriteFile() {
+	s.Assert().NoError(atomicfile.WriteFile(s.pth, s.dta, s.prm))
+
+	dta, err := os.ReadFile(s.pth)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.dta, dta)
+
+	fst, err := os.Stat(s.pth)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.prm, fst.Mode().Perm())
+
+	// temporary files are not left behind
+	ets, err := os.ReadDir(filepath.Dir(s.pth))
+	s.Assert().NoError(err)
+	s.Assert().Len(ets, 1)
+}
+
+func TestWriteFile(t *testing.T) {
+	for _, testCase := range []*writeFileTestSuite{
+		{
+			dta: []byte(`{"enwiki":{"0":10}}`),
+			prm: 0600,
+		},
+		{
+			old: []byte(`{"enwiki":{"0":10,"1":20}}`),
+			dta: []byte(`{}`),
+			prm: 0640,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -5,32 +5,32 @@ package api
 
 import (
 	"archive/tar"
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/klauspost/pgzip"
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/protsack-stephan/wme/pkg/filter"
+	"github.com/protsack-stephan/wme/pkg/idle"
+	"github.com/protsack-stephan/wme/pkg/ndjson"
+	"github.com/protsack-stephan/wme/pkg/ratelimit"
 	"github.com/protsack-stephan/wme/schema/v2"
 )
 
 const dateFormat = "2006-01-02"
 
 // Filter represents a filter to be applied to a dataset.
-type Filter struct {
-	// Field specifies the field in the dataset that the filter should be applied to.
-	Field string `json:"field"`
-
-	// Value specifies the value that the field should be compared to.
-	Value interface{} `json:"value"`
-}
+// Can be evaluated locally with the filter package, see ReadFilters.
+type Filter = filter.Filter
 
 // ReadCallback is a function that will be called with each Article object that is read from a batch or snapshot.
 // You can return a custom error to stop the reading.
@@ -54,6 +54,19 @@ type Request struct {
 	// Limits the amount of results from the API (for now works only with Articles API).
 	// This is an optional argument.
 	Limit int `json:"limit,omitempty"`
+
+	// Parts is a parameter used only for streaming endpoints.
+	// Stream is split into 10 parts of 5 partitions each (part 0 is partitions 0 through 4, part 1 is 5 through 9 etc.),
+	// so the load can be split between multiple connections. All of the partitions are streamed if empty.
+	Parts []int `json:"parts,omitempty"`
+
+	// Offsets is a parameter used only for streaming endpoints.
+	// Will pick up the reading of the partition (key) from the offset (value), partitions outside of the Parts are ignored.
+	Offsets map[int]int64 `json:"offsets,omitempty"`
+
+	// SincePerPartition is a parameter used only for streaming endpoints.
+	// Same as Since but per partition (key), partitions outside of the Parts are ignored.
+	SincePerPartition map[int]time.Time `json:"since_per_partition,omitempty"`
 }
 
 // CodesGetter is an interface that retrieves codes from the API.
@@ -116,11 +129,21 @@ type BatchReader interface {
 	ReadBatch(ctx context.Context, dte *time.Time, idr string, cbk ReadCallback) error
 }
 
+// BatchIterator is an interface that returns an iterator over a realtime batch data by ID from the API.
+type BatchIterator interface {
+	IterateBatch(ctx context.Context, dte *time.Time, idr string) *Iterator
+}
+
 // BatchDownloader is an interface that downloads a realtime batch `tar.gz` by ID file from the API.
 type BatchDownloader interface {
 	DownloadBatch(ctx context.Context, dte *time.Time, idr string, wsk io.WriteSeeker) error
 }
 
+// BatchDownloaderAt is an interface that downloads a realtime batch `tar.gz` by ID file from the API, writing chunks concurrently at their offsets.
+type BatchDownloaderAt interface {
+	DownloadBatchAt(ctx context.Context, dte *time.Time, idr string, wat io.WriterAt) error
+}
+
 // SnapshotsGetter is an interface for getting multiple snapshots.
 type SnapshotsGetter interface {
 	GetSnapshots(ctx context.Context, req *Request) ([]*schema.Snapshot, error)
@@ -141,11 +164,21 @@ type SnapshotDownloader interface {
 	DownloadSnapshot(ctx context.Context, idr string, wsk io.WriteSeeker) error
 }
 
+// SnapshotDownloaderAt is an interface for downloading a single snapshot by ID, writing chunks concurrently at their offsets.
+type SnapshotDownloaderAt interface {
+	DownloadSnapshotAt(ctx context.Context, idr string, wat io.WriterAt) error
+}
+
 // SnapshotReader is an interface for reading the contents of a single snapshot by ID with a callback function.
 type SnapshotReader interface {
 	ReadSnapshot(ctx context.Context, idr string, cbk ReadCallback) error
 }
 
+// SnapshotIterator is an interface for iterating over the contents of a single snapshot by ID.
+type SnapshotIterator interface {
+	IterateSnapshot(ctx context.Context, idr string) *Iterator
+}
+
 // ArticlesGetter is an interface for getting a lits of articles by name.
 type ArticlesGetter interface {
 	GetArticles(ctx context.Context, nme string, req *Request) ([]*schema.Article, error)
@@ -161,18 +194,40 @@ type ArticlesStreamer interface {
 	StreamArticles(ctx context.Context, req *Request, cbk ReadCallback) error
 }
 
+// ArticlesStreamIterator is an interface for iterating over all the article changes in realtime.
+type ArticlesStreamIterator interface {
+	IterateArticles(ctx context.Context, req *Request) *Iterator
+}
+
 // AllReader is an interface for reading all the contents of a reader with a callback function.
 type AllReader interface {
 	ReadAll(ctx context.Context, rdr io.Reader, cbk ReadCallback) error
 }
 
+// AllIterator is an interface for iterating over all the contents of a reader.
+type AllIterator interface {
+	IterateAll(ctx context.Context, rdr io.Reader) *Iterator
+}
+
 // AccessTokenSetter is an interface for setting an access token.
 type AccessTokenSetter interface {
 	SetAccessToken(tkn string)
 }
 
+// TokenSourceSetter is an interface for setting a source of access tokens.
+type TokenSourceSetter interface {
+	SetTokenSource(tks auth.TokenGetter)
+}
+
+// QuotaGetter is an interface for getting the quota usage reported by the API.
+type QuotaGetter interface {
+	GetQuota() *ratelimit.Quota
+}
+
 // API interface tha encapsulates the whole functionality of the client.
 // Can be used with composition in unit testing.
+// Interfaces added later (iterators, downloaders at offsets, token source and quota) are not embedded,
+// so existing implementations keep compiling, use a type assertion to get those from the client.
 type API interface {
 	CodesGetter
 	CodeGetter
@@ -208,6 +263,10 @@ func NewClient(ops ...func(clt *Client)) API {
 		DownloadMinChunkSize: 5242880,
 		DownloadChunkSize:    5242880 * 5,
 		DownloadConcurrency:  10,
+		DownloadMaxMemory:    10485760,
+		ReadChunkSize:        5242880,
+		MaxLineSize:          ndjson.DefaultMaxLineSize,
+		ProgressInterval:     time.Second,
 		UserAgent:            "",
 		BaseUrl:              "https://api.enterprise.wikimedia.com/",
 		RealtimeURL:          "https://realtime.enterprise.wikimedia.com/",
@@ -222,14 +281,34 @@ func NewClient(ops ...func(clt *Client)) API {
 
 // Client is a struct that represents an HTTP client used to interact with the API.
 type Client struct {
-	HTTPClient           *http.Client // HTTP client used to send requests.
-	UserAgent            string       // User-agent header value sent with each request.
-	BaseUrl              string       // Base URL for all API requests.
-	RealtimeURL          string       // Streaming URL endpoint for streaming.
-	AccessToken          string       // Access token used to authenticate requests.
-	DownloadMinChunkSize int          // Minimum chunk size used for downloading resources.
-	DownloadChunkSize    int          // Chunk size used for downloading resources.
-	DownloadConcurrency  int          // Number of simultaneous downloads allowed.
+	HTTPClient           *http.Client              // HTTP client used to send requests.
+	UserAgent            string                    // User-agent header value sent with each request.
+	BaseUrl              string                    // Base URL for all API requests.
+	RealtimeURL          string                    // Streaming URL endpoint for streaming.
+	AccessToken          string                    // Access token used to authenticate requests.
+	TokenSource          auth.TokenGetter          // Source of access tokens, takes precedence over AccessToken.
+	DownloadMinChunkSize int                       // Minimum chunk size used for downloading resources.
+	DownloadChunkSize    int                       // Chunk size used for downloading resources.
+	DownloadConcurrency  int                       // Number of simultaneous downloads allowed.
+	DownloadMaxMemory    int                       // Upper limit for memory used to buffer the downloads, split between the workers.
+	DownloadCheckpoint   bool                      // Resume downloads into files using a sidecar `.checkpoint` file.
+	DownloadVerify       bool                      // Verify downloaded content against the published checksums (ErrNoChecksum if none), writer needs to implement io.ReaderAt.
+	ReadConcurrency      int                       // Number of ranges fetched in parallel by ReadSnapshot and ReadBatch, single request if less than 2.
+	ReadChunkSize        int                       // Size of the range fetched by parallel reads, memory usage is around ReadChunkSize × (ReadConcurrency + 1).
+	ReadFilters          []*Filter                 // Filters applied to the articles when reading, articles that don't match are skipped.
+	ReadFields           []string                  // Fields to decode when reading (same syntax as Request.Fields), the rest of the line is skipped.
+	MaxLineSize          int                       // Maximum size of a single line (article) in bytes, longer lines are handled as decode errors.
+	DecodeErrorHandler   ndjson.DecodeErrorHandler // Decides whether to skip or stop on lines that can't be decoded, reading stops if nil.
+	IdleTimeout          time.Duration             // Closes the stream if a read waits for the data (including keepalive) for the duration, the error is *idle.TimeoutError, disabled if 0.
+	Stats                ReadStatsCallback         // Callback with the counters of read and skipped articles, called at the end of each read.
+	DecodeConcurrency    int                       // Number of workers decoding the articles, callback and decode error handler are called concurrently if more than one.
+	DecodeOrdered        bool                      // Call the callback (and the decode error handler) sequentially in original order when decoding concurrently.
+	Progress             ProgressCallback          // Callback for download and read progress, no reporting if nil.
+	ProgressInterval     time.Duration             // Minimum interval between progress reports, chunk completion is always reported.
+	RetryPolicy          *RetryPolicy              // Retry policy for failed requests, no retries if nil.
+	RateLimits           *RateLimits               // Client side rate limits per endpoint group, no limits if nil.
+	mut                  sync.Mutex
+	quota                *ratelimit.Quota
 }
 
 func (c *Client) newRequest(ctx context.Context, url string, mtd string, pth string, req *Request) (*http.Request, error) {
@@ -245,6 +324,11 @@ func (c *Client) newRequest(ctx context.Context, url string, mtd string, pth str
 		dta = bdy
 	}
 
+	// every request is rate limited, including the stream connections and the chunks of downloads
+	if err := c.wait(ctx, pth); err != nil {
+		return nil, err
+	}
+
 	hrq, err := http.NewRequestWithContext(ctx, mtd, fmt.Sprintf("%sv2/%s", url, pth), bytes.NewReader(dta))
 
 	if err != nil {
@@ -253,11 +337,25 @@ func (c *Client) newRequest(ctx context.Context, url string, mtd string, pth str
 
 	hrq.Header.Set("User-Agent", c.UserAgent)
 	hrq.Header.Set("Content-Type", "application/json")
-	hrq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.AccessToken))
+	tkn, err := c.getAccessToken(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hrq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tkn))
 
 	return hrq, nil
 }
 
+func (c *Client) getAccessToken(ctx context.Context) (string, error) {
+	if c.TokenSource != nil {
+		return c.TokenSource.GetToken(ctx)
+	}
+
+	return c.AccessToken, nil
+}
+
 func (c *Client) do(hrq *http.Request) (*http.Response, error) {
 	res, err := c.HTTPClient.Do(hrq)
 
@@ -265,52 +363,74 @@ func (c *Client) do(hrq *http.Request) (*http.Response, error) {
 		return nil, err
 	}
 
+	if qta := ratelimit.ParseQuota(res.Header); qta != nil {
+		c.mut.Lock()
+		c.quota = qta
+		c.mut.Unlock()
+	}
+
 	if res.StatusCode < http.StatusOK || res.StatusCode > http.StatusIMUsed {
-		dta, err := io.ReadAll(res.Body)
 		defer res.Body.Close()
-
-		if err != nil {
-			return nil, err
-		}
-
-		if len(string(dta)) == 0 {
-			return nil, errors.New(res.Status)
-		}
-
-		return nil, errors.New(string(dta))
+		return nil, apierror.New(res)
 	}
 
 	return res, nil
 }
 
+// getEntity is a read only lookup, so it's safe to retry even though it uses POST method.
 func (c *Client) getEntity(ctx context.Context, req *Request, pth string, val interface{}) error {
-	hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodPost, pth, req)
+	return c.retry(ctx, func() error {
+		hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodPost, pth, req)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	res, err := c.do(hrq)
+		res, err := c.do(hrq)
 
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+
+		defer res.Body.Close()
+		return json.NewDecoder(res.Body).Decode(val)
+	})
+}
+
+// contextReader fails the reads once the context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	rdr io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
 
-	defer res.Body.Close()
-	return json.NewDecoder(res.Body).Decode(val)
+	return r.rdr.Read(p)
 }
 
 func (c *Client) readAll(ctx context.Context, rdr io.Reader, cbk ReadCallback) error {
-	gzr, err := pgzip.NewReader(rdr)
+	sts := new(ReadStats)
+	defer c.Stats.report(sts)
+
+	// pgzip reads ahead in the background, so the reader has to fail once the context is cancelled
+	gzr, err := pgzip.NewReader(&contextReader{ctx: ctx, rdr: rdr})
 
 	if err != nil {
 		return err
 	}
 
+	defer gzr.Close()
 	trr := tar.NewReader(gzr)
 
 	for {
-		_, err := trr.Next()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := trr.Next()
 
 		if err == io.EOF {
 			break
@@ -320,7 +440,9 @@ func (c *Client) readAll(ctx context.Context, rdr io.Reader, cbk ReadCallback) e
 			return err
 		}
 
-		if err := c.readLoop(ctx, trr, cbk); err != nil {
+		sts.Entries++
+
+		if err := c.readLoop(ctx, hdr.Name, trr, sts, cbk); err != nil {
 			return err
 		}
 	}
@@ -328,188 +450,178 @@ func (c *Client) readAll(ctx context.Context, rdr io.Reader, cbk ReadCallback) e
 	return nil
 }
 
-func (c *Client) readLoop(ctx context.Context, rdr io.Reader, cbk ReadCallback) error {
-	scn := bufio.NewScanner(rdr)
-	scn.Buffer([]byte{}, 20971520)
+func (c *Client) readLoop(ctx context.Context, ent string, rdr io.Reader, sts *ReadStats, cbk ReadCallback) error {
+	if c.DecodeConcurrency > 1 {
+		return c.readLoopConcurrent(ctx, ent, rdr, sts, cbk)
+	}
 
-	for scn.Scan() {
-		art := new(schema.Article)
+	lrd := ndjson.NewReader(rdr, c.MaxLineSize)
+	fdt := c.readFieldTree()
 
-		if err := json.Unmarshal(scn.Bytes(), art); err != nil {
+	for {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		if err := cbk(art); err != nil {
-			return err
-		}
-	}
+		lne, err := lrd.ReadLine()
 
-	return nil
-}
-
-func (c *Client) readEntity(ctx context.Context, pth string, cbk ReadCallback) error {
-	hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodGet, pth, nil)
+		if err == io.EOF {
+			break
+		}
 
-	if err != nil {
-		return err
-	}
+		if err != nil && err != ndjson.ErrLineTooLong {
+			return err
+		}
 
-	res, err := c.do(hrq)
+		art := new(schema.Article)
 
-	if err != nil {
-		return err
-	}
+		if err == nil {
+			err = decodeArticle(lne, fdt, art)
+		}
 
-	defer res.Body.Close()
-	return c.readAll(ctx, res.Body, cbk)
-}
+		if err != nil {
+			if err := c.DecodeErrorHandler.Handle(ndjson.NewDecodeError(ent, lrd.Line(), lrd.Offset(), lne, err)); err != nil {
+				return err
+			}
 
-func (c *Client) headEntity(ctx context.Context, pth string) (*schema.Headers, error) {
-	hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodHead, pth, nil)
+			sts.skip()
+			continue
+		}
 
-	if err != nil {
-		return nil, err
-	}
+		if !filter.Match(art, c.ReadFilters...) {
+			sts.filter()
+			continue
+		}
 
-	res, err := c.do(hrq)
+		sts.article()
 
-	if err != nil {
-		return nil, err
+		if err := cbk(art); err != nil {
+			return err
+		}
 	}
 
-	hdr := &schema.Headers{
-		ETag:         strings.Trim(res.Header.Get("ETag"), "\""),
-		ContentType:  res.Header.Get("Content-Type"),
-		AcceptRanges: res.Header.Get("Accept-Ranges"),
-	}
+	return ctx.Err()
+}
 
-	if lmf := res.Header.Get("Last-Modified"); len(lmf) > 0 {
-		lmd, err := time.Parse(time.RFC1123, lmf)
+func (c *Client) readEntity(ctx context.Context, pth string, cbk ReadCallback) error {
+	if c.ReadConcurrency > 1 {
+		hds, err := c.headEntity(ctx, pth)
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		hdr.LastModified = &lmd
-	}
-
-	if ctl := res.Header.Get("Content-Length"); len(ctl) > 0 {
-		cti, err := strconv.Atoi(ctl)
+		// fallback to a single request if server can't serve the ranges
+		if hds.AcceptRanges == "bytes" && hds.ContentLength > 0 {
+			rdr := c.newRangeReader(ctx, pth, hds)
+			defer rdr.Close()
 
-		if err != nil {
-			return nil, err
+			return c.readTracked(ctx, pth, rdr, int64(hds.ContentLength), cbk)
 		}
-
-		hdr.ContentLength = cti
 	}
 
-	return hdr, nil
-}
+	var res *http.Response
 
-type chunk struct {
-	start int
-	end   int
-	data  []byte
-}
+	err := c.retry(ctx, func() error {
+		hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodGet, pth, nil)
+
+		if err != nil {
+			return err
+		}
 
-func (c *Client) downloadEntity(ctx context.Context, pth string, wrr io.WriteSeeker) error {
-	hds, err := c.headEntity(ctx, pth)
+		res, err = c.do(hrq)
+		return err
+	})
 
 	if err != nil {
 		return err
 	}
 
-	csz := c.DownloadChunkSize
+	irg := res.Header.Get("ETag")
 
-	if hds.ContentLength < c.DownloadMinChunkSize {
-		csz = c.DownloadMinChunkSize
+	if len(irg) == 0 {
+		irg = res.Header.Get("Last-Modified")
 	}
 
-	cks := []*chunk{}
+	rdr := &resumableReader{
+		ctx: ctx,
+		clt: c,
+		pth: pth,
+		irg: irg,
+		bdy: res.Body,
+	}
 
-	for i := 0; true; i++ {
-		cnk := &chunk{
-			start: i * csz,
-			end:   (i * csz) + csz,
-		}
+	defer rdr.Close()
+	btl := res.ContentLength
 
-		if cnk.end > hds.ContentLength {
-			cnk.end = hds.ContentLength
-		}
+	if btl < 0 {
+		btl = 0
+	}
 
-		cks = append(cks, cnk)
+	return c.readTracked(ctx, pth, rdr, btl, cbk)
+}
 
-		if cnk.end == hds.ContentLength {
-			break
-		}
+// readTracked reads the whole entity, reporting the progress if needed.
+func (c *Client) readTracked(ctx context.Context, pth string, rdr io.Reader, btl int64, cbk ReadCallback) error {
+	prt := c.newProgressTracker(pth, btl, 0)
+
+	if err := c.readAll(ctx, &progressReader{rdr: rdr, prt: prt}, cbk); err != nil {
+		return err
 	}
 
-	ers := make(chan error, len(cks)*2)
-	cds := make(chan *chunk, len(cks))
+	prt.finish()
+	return nil
+}
 
-	go func() {
-		for cnk := range cds {
-			if _, err := wrr.Seek(int64(cnk.start), 0); err != nil {
-				ers <- err
-				return
-			}
+func (c *Client) headEntity(ctx context.Context, pth string) (*schema.Headers, error) {
+	var res *http.Response
 
-			if _, err := io.CopyN(wrr, bytes.NewReader(cnk.data), int64(cnk.end-cnk.start)); err != nil {
-				ers <- err
-				return
-			}
+	err := c.retry(ctx, func() error {
+		hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodHead, pth, nil)
 
-			ers <- nil
+		if err != nil {
+			return err
 		}
-	}()
-
-	dcs := c.DownloadConcurrency
-	smr := make(chan struct{}, dcs)
 
-	for _, cnk := range cks {
-		go func(cnk *chunk) {
-			smr <- struct{}{}
-			defer func() {
-				ers <- nil
-				<-smr
-			}()
-
-			hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodGet, pth, nil)
-			hrq.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", cnk.start, cnk.end))
+		res, err = c.do(hrq)
+		return err
+	})
 
-			if err != nil {
-				ers <- err
-				return
-			}
+	if err != nil {
+		return nil, err
+	}
 
-			res, err := c.do(hrq)
+	defer res.Body.Close()
 
-			if err != nil {
-				ers <- err
-				return
-			}
+	hdr := &schema.Headers{
+		ETag:           strings.Trim(res.Header.Get("ETag"), "\""),
+		ContentType:    res.Header.Get("Content-Type"),
+		AcceptRanges:   res.Header.Get("Accept-Ranges"),
+		ContentMD5:     res.Header.Get("Content-MD5"),
+		ChecksumSHA256: res.Header.Get("X-Amz-Checksum-Sha256"),
+	}
 
-			defer res.Body.Close()
-			cnk.data, err = io.ReadAll(res.Body)
+	if lmf := res.Header.Get("Last-Modified"); len(lmf) > 0 {
+		lmd, err := time.Parse(time.RFC1123, lmf)
 
-			if err != nil {
-				ers <- err
-				return
-			}
+		if err != nil {
+			return nil, err
+		}
 
-			cds <- cnk
-		}(cnk)
+		hdr.LastModified = &lmd
 	}
 
-	for i := 0; i < cap(ers); i++ {
-		if err := <-ers; err != nil {
-			return err
+	if ctl := res.Header.Get("Content-Length"); len(ctl) > 0 {
+		cti, err := strconv.Atoi(ctl)
+
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	close(cds)
+		hdr.ContentLength = cti
+	}
 
-	return nil
+	return hdr, nil
 }
 
 func (c *Client) subscribeToEntity(ctx context.Context, pth string, req *Request, cbk ReadCallback) error {
@@ -528,8 +640,13 @@ func (c *Client) subscribeToEntity(ctx context.Context, pth string, req *Request
 		return err
 	}
 
-	defer res.Body.Close()
-	return c.readLoop(ctx, res.Body, cbk)
+	// connection can stall without being closed, so it's closed by the idle reader instead
+	bdy := idle.NewReader(res.Body, c.IdleTimeout)
+	defer bdy.Close()
+	sts := new(ReadStats)
+	defer c.Stats.report(sts)
+
+	return c.readLoop(ctx, "", bdy, sts, cbk)
 }
 
 // SetAccessToken sets the access token for the client.
@@ -537,6 +654,20 @@ func (c *Client) SetAccessToken(tkn string) {
 	c.AccessToken = tkn
 }
 
+// SetTokenSource sets the source of access tokens for the client.
+// Once set, the token will be requested from the source before each request.
+func (c *Client) SetTokenSource(tks auth.TokenGetter) {
+	c.TokenSource = tks
+}
+
+// GetQuota returns the latest quota usage reported in the response headers, nil if API didn't report one.
+func (c *Client) GetQuota() *ratelimit.Quota {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.quota
+}
+
 // GetCodes retrieves a list of codes, and returns an error if any.
 func (c *Client) GetCodes(ctx context.Context, req *Request) ([]*schema.Code, error) {
 	cds := []*schema.Code{}
@@ -607,9 +738,21 @@ func (c *Client) ReadBatch(ctx context.Context, dte *time.Time, idr string, cbk
 	return c.readEntity(ctx, fmt.Sprintf("batches/%s/%s/download", dte.Format(dateFormat), idr), cbk)
 }
 
+// IterateBatch returns an iterator over the contents of a single batch for a specific date and ID.
+func (c *Client) IterateBatch(ctx context.Context, dte *time.Time, idr string) *Iterator {
+	return newIterator(ctx, func(ctx context.Context, cbk ReadCallback) error {
+		return c.ReadBatch(ctx, dte, idr, cbk)
+	})
+}
+
 // DownloadBatch downloads the contents of a single batch for a specific date and ID, and writes the data to the specified WriteSeeker.
 func (c *Client) DownloadBatch(ctx context.Context, dte *time.Time, idr string, wsk io.WriteSeeker) error {
-	return c.downloadEntity(ctx, fmt.Sprintf("batches/%s/%s/download", dte.Format(dateFormat), idr), wsk)
+	return c.downloadEntity(ctx, fmt.Sprintf("batches/%s/%s/download", dte.Format(dateFormat), idr), newWriterAt(wsk))
+}
+
+// DownloadBatchAt downloads the contents of a single batch for a specific date and ID, and writes the chunks concurrently to the specified WriterAt.
+func (c *Client) DownloadBatchAt(ctx context.Context, dte *time.Time, idr string, wat io.WriterAt) error {
+	return c.downloadEntity(ctx, fmt.Sprintf("batches/%s/%s/download", dte.Format(dateFormat), idr), wat)
 }
 
 // GetSnapshots retrieves a list of all snapshots and returns an error if any.
@@ -634,9 +777,21 @@ func (c *Client) ReadSnapshot(ctx context.Context, idr string, cbk ReadCallback)
 	return c.readEntity(ctx, fmt.Sprintf("snapshots/%s/download", idr), cbk)
 }
 
+// IterateSnapshot returns an iterator over the contents of a single snapshot for a specific ID.
+func (c *Client) IterateSnapshot(ctx context.Context, idr string) *Iterator {
+	return newIterator(ctx, func(ctx context.Context, cbk ReadCallback) error {
+		return c.ReadSnapshot(ctx, idr, cbk)
+	})
+}
+
 // DownloadSnapshot downloads the contents of a single snapshot for a specific ID, and writes the data to the specified WriteSeeker.
 func (c *Client) DownloadSnapshot(ctx context.Context, idr string, wsk io.WriteSeeker) error {
-	return c.downloadEntity(ctx, fmt.Sprintf("snapshots/%s/download", idr), wsk)
+	return c.downloadEntity(ctx, fmt.Sprintf("snapshots/%s/download", idr), newWriterAt(wsk))
+}
+
+// DownloadSnapshotAt downloads the contents of a single snapshot for a specific ID, and writes the chunks concurrently to the specified WriterAt.
+func (c *Client) DownloadSnapshotAt(ctx context.Context, idr string, wat io.WriterAt) error {
+	return c.downloadEntity(ctx, fmt.Sprintf("snapshots/%s/download", idr), wat)
 }
 
 // GetArticles retrieves articles from the API based on the given name and request parameters.
@@ -653,6 +808,7 @@ func (c *Client) GetThings(ctx context.Context, nme string, req *Request) ([]*sc
 
 // StreamArticles streams all available articles from the server and applies a callback function to each article
 // as they arrive. The callback function must implement the ReadCallback interface.
+// Use Parts to split the stream between connections, and Offsets or SincePerPartition to resume each of the partitions.
 func (c *Client) StreamArticles(ctx context.Context, req *Request, cbk ReadCallback) error {
 	return c.subscribeToEntity(ctx, "articles", req, cbk)
 }
@@ -662,3 +818,17 @@ func (c *Client) StreamArticles(ctx context.Context, req *Request, cbk ReadCallb
 func (c *Client) ReadAll(ctx context.Context, rdr io.Reader, cbk ReadCallback) error {
 	return c.readAll(ctx, rdr, cbk)
 }
+
+// IterateArticles returns an iterator over all available articles streamed from the server.
+func (c *Client) IterateArticles(ctx context.Context, req *Request) *Iterator {
+	return newIterator(ctx, func(ctx context.Context, cbk ReadCallback) error {
+		return c.StreamArticles(ctx, req, cbk)
+	})
+}
+
+// IterateAll returns an iterator over the contents of the given io.Reader.
+func (c *Client) IterateAll(ctx context.Context, rdr io.Reader) *Iterator {
+	return newIterator(ctx, func(ctx context.Context, cbk ReadCallback) error {
+		return c.ReadAll(ctx, rdr, cbk)
+	})
+}
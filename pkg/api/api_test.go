@@ -1,10 +1,15 @@
 package api_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,6 +26,35 @@ func createHandler(sts int, dta string) http.HandlerFunc {
 	}
 }
 
+func createArchive(fls map[string][]string) []byte {
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	trw := tar.NewWriter(gzw)
+	nms := []string{}
+
+	for nme := range fls {
+		nms = append(nms, nme)
+	}
+
+	sort.Strings(nms)
+
+	for _, nme := range nms {
+		dta := []byte(strings.Join(fls[nme], "\n"))
+
+		_ = trw.WriteHeader(&tar.Header{
+			Name: nme,
+			Mode: 0600,
+			Size: int64(len(dta)),
+		})
+		_, _ = trw.Write(dta)
+	}
+
+	_ = trw.Close()
+	_ = gzw.Close()
+
+	return buf.Bytes()
+}
+
 type apiTestSuite struct {
 	suite.Suite
 	clt api.API
@@ -110,6 +144,25 @@ func (s *apiTestSuite) TestSetAccessToken() {
 	s.Assert().Equal(s.act, s.clt.(*api.Client).AccessToken)
 }
 
+type tokenGetter struct {
+	tkn string
+}
+
+func (t *tokenGetter) GetToken(_ context.Context) (string, error) {
+	return t.tkn, nil
+}
+
+func (s *apiTestSuite) TestSetTokenSource() {
+	tks := &tokenGetter{tkn: s.act}
+	tss, ok := s.clt.(api.TokenSourceSetter)
+	s.Assert().True(ok)
+
+	tss.SetTokenSource(tks)
+	defer tss.SetTokenSource(nil)
+
+	s.Assert().Equal(tks, s.clt.(*api.Client).TokenSource)
+}
+
 func (s *apiTestSuite) TestGetCodes() {
 	cds, err := s.clt.GetCodes(s.ctx, s.req)
 
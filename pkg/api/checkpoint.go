@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// checkpointHeader identifies the remote content the checkpoint was created for.
+type checkpointHeader struct {
+	ETag          string     `json:"etag"`
+	LastModified  *time.Time `json:"last_modified,omitempty"`
+	ContentLength int        `json:"content_length"`
+}
+
+func (h *checkpointHeader) matches(hds *schema.Headers) bool {
+	if h.ETag != hds.ETag || h.ContentLength != hds.ContentLength {
+		return false
+	}
+
+	if h.LastModified == nil || hds.LastModified == nil {
+		return h.LastModified == hds.LastModified
+	}
+
+	return h.LastModified.Equal(*hds.LastModified)
+}
+
+// checkpointRange is a byte range that was fully written to the target.
+type checkpointRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// checkpoint is a sidecar file that keeps track of completed byte ranges of the download.
+// First line is the header, every other line is a completed range.
+// Lines are only appended, partially written last line (if the process died) is cut off on open, so the next ranges are appended after the complete lines.
+// Ranges are recorded only after the target was synced (if it supports it), so the checkpoint never gets ahead of the data on disk.
+// Checkpoint is discarded if the target is shorter than the recorded ranges (for example it was truncated on open).
+type checkpoint struct {
+	mut sync.Mutex
+	pth string
+	fle *os.File
+	tgt interface{}
+	rgs map[checkpointRange]bool
+}
+
+func openCheckpoint(pth string, hds *schema.Headers, tgt interface{}) (*checkpoint, error) {
+	ckp := &checkpoint{
+		pth: pth,
+		tgt: tgt,
+		rgs: map[checkpointRange]bool{},
+	}
+
+	vld, end, err := ckp.load(hds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if vld {
+		vld, err = ckp.covered()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// cut off the partially written line, otherwise the next range is glued to it
+	if vld {
+		if err := os.Truncate(pth, end); err != nil {
+			return nil, err
+		}
+	}
+
+	flg := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+
+	if !vld {
+		flg |= os.O_TRUNC
+		ckp.rgs = map[checkpointRange]bool{}
+
+		// content of the target belongs to a different download (or none), so it's downloaded from scratch
+		if tct, ok := tgt.(interface{ Truncate(size int64) error }); ok {
+			if err := tct.Truncate(0); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ckp.fle, err = os.OpenFile(pth, flg, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !vld {
+		if err := ckp.append(&checkpointHeader{
+			ETag:          hds.ETag,
+			LastModified:  hds.LastModified,
+			ContentLength: hds.ContentLength,
+		}); err != nil {
+			_ = ckp.fle.Close()
+			return nil, err
+		}
+	}
+
+	return ckp, nil
+}
+
+// load reads the existing checkpoint, returns false if there's none or it was created for different content.
+// Also returns the size of the complete lines, anything after that is a partially written line.
+func (c *checkpoint) load(hds *schema.Headers) (bool, int64, error) {
+	fle, err := os.Open(c.pth)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return false, 0, nil
+	}
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	defer fle.Close()
+	rdr := bufio.NewReader(fle)
+	lne, err := rdr.ReadBytes('\n')
+
+	if err != nil && err != io.EOF {
+		return false, 0, err
+	}
+
+	hdr := new(checkpointHeader)
+
+	if !bytes.HasSuffix(lne, []byte{'\n'}) || json.Unmarshal(lne, hdr) != nil || !hdr.matches(hds) {
+		return false, 0, nil
+	}
+
+	end := int64(len(lne))
+
+	for {
+		lne, err := rdr.ReadBytes('\n')
+
+		if err != nil && err != io.EOF {
+			return false, 0, err
+		}
+
+		rng := checkpointRange{}
+
+		if !bytes.HasSuffix(lne, []byte{'\n'}) || json.Unmarshal(lne, &rng) != nil {
+			break
+		}
+
+		c.rgs[rng] = true
+		end += int64(len(lne))
+	}
+
+	return true, end, nil
+}
+
+// covered checks that the target holds all of the recorded ranges, targets that can't be checked are trusted.
+func (c *checkpoint) covered() (bool, error) {
+	stt, ok := c.tgt.(interface{ Stat() (os.FileInfo, error) })
+
+	if !ok {
+		return true, nil
+	}
+
+	inf, err := stt.Stat()
+
+	if err != nil {
+		return false, err
+	}
+
+	for rng := range c.rgs {
+		if int64(rng.End) > inf.Size() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (c *checkpoint) append(v interface{}) error {
+	dta, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.fle.Write(append(dta, '\n'))
+	return err
+}
+
+// isDone checks if the range was already written.
+func (c *checkpoint) isDone(start, end int) bool {
+	return c.rgs[checkpointRange{Start: start, End: end}]
+}
+
+// done records the range as written, the target is synced first, so the range can't get lost in case of a crash.
+func (c *checkpoint) done(start, end int) error {
+	rng := checkpointRange{Start: start, End: end}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if syn, ok := c.tgt.(interface{ Sync() error }); ok {
+		if err := syn.Sync(); err != nil {
+			return fmt.Errorf("can't sync downloaded range: %w", err)
+		}
+	}
+
+	if err := c.append(&rng); err != nil {
+		return fmt.Errorf("can't update download checkpoint: %w", err)
+	}
+
+	if err := c.fle.Sync(); err != nil {
+		return fmt.Errorf("can't sync download checkpoint: %w", err)
+	}
+
+	c.rgs[rng] = true
+	return nil
+}
+
+// Close closes the checkpoint file, keeping it on disk.
+func (c *checkpoint) Close() error {
+	return c.fle.Close()
+}
+
+// remove closes and deletes the checkpoint file, used once the download is complete.
+func (c *checkpoint) remove() error {
+	_ = c.fle.Close()
+	return os.Remove(c.pth)
+}
@@ -0,0 +1,230 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/stretchr/testify/suite"
+)
+
+type checkpointTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	clt api.API
+	dta []byte
+	lmd time.Time
+	etg string
+	ckp []string
+	trn bool
+	fal int
+	pre int
+	stl []byte
+	rgs int
+	mut sync.Mutex
+}
+
+func (s *checkpointTestSuite) SetupTest() {
+	s.rgs = 0
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rng := r.Header.Get("Range"); len(rng) > 0 {
+			s.mut.Lock()
+			s.rgs++
+			fal := s.fal
+			s.mut.Unlock()
+
+			// ranges after the offset fail, to interrupt the download
+			off, _ := strconv.Atoi(strings.Split(strings.TrimPrefix(rng, "bytes="), "-")[0])
+
+			if fal > 0 && off >= fal {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, "", s.lmd, bytes.NewReader(s.dta))
+	}))
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.BaseUrl = fmt.Sprintf("%s/", s.srv.URL)
+		clt.DownloadChunkSize = 10
+		clt.DownloadMinChunkSize = 10
+		clt.DownloadConcurrency = 2
+		clt.DownloadCheckpoint = true
+	})
+}
+
+func (s *checkpointTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+// writeCheckpoint creates the sidecar file, the last line is left without the line break if the checkpoint is torn.
+func (s *checkpointTestSuite) writeCheckpoint(pth string) {
+	if len(s.ckp) == 0 {
+		return
+	}
+
+	dta := strings.Join(s.ckp, "\n")
+
+	if !s.trn {
+		dta += "\n"
+	}
+
+	s.Assert().NoError(os.WriteFile(fmt.Sprintf("%s.checkpoint", pth), []byte(dta), 0644))
+}
+
+func (s *checkpointTestSuite) TestDownloadSnapshot() {
+	if s.fal > 0 {
+		s.T().Skip("interrupted downloads are covered separately")
+	}
+
+	pth := filepath.Join(s.T().TempDir(), "enwiki_namespace_0.tar.gz")
+	fle, err := os.OpenFile(pth, os.O_CREATE|os.O_RDWR, 0644)
+	s.Assert().NoError(err)
+	defer fle.Close()
+
+	// emulate previously interrupted download, with first chunks written to disk
+	_, err = fle.Write(s.dta[:s.pre])
+	s.Assert().NoError(err)
+
+	// content left from a different download
+	_, err = fle.Write(s.stl)
+	s.Assert().NoError(err)
+
+	s.writeCheckpoint(pth)
+	s.Assert().NoError(s.clt.DownloadSnapshot(s.ctx, "enwiki_namespace_0", fle))
+
+	dta, err := os.ReadFile(pth)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.dta, dta)
+	s.Assert().Equal(len(s.dta)/10-s.pre/10, s.rgs)
+
+	_, err = os.Stat(fmt.Sprintf("%s.checkpoint", pth))
+	s.Assert().ErrorIs(err, os.ErrNotExist)
+}
+
+func (s *checkpointTestSuite) TestInterruptedDownload() {
+	if s.fal == 0 {
+		s.T().Skip("only for interrupted downloads")
+	}
+
+	pth := filepath.Join(s.T().TempDir(), "enwiki_namespace_0.tar.gz")
+	fle, err := os.OpenFile(pth, os.O_CREATE|os.O_RDWR, 0644)
+	s.Assert().NoError(err)
+	defer fle.Close()
+
+	_, err = fle.Write(s.dta[:s.pre])
+	s.Assert().NoError(err)
+
+	s.writeCheckpoint(pth)
+	s.Assert().Error(s.clt.DownloadSnapshot(s.ctx, "enwiki_namespace_0", fle))
+
+	// every line is complete, ranges recorded after the torn line are not lost
+	dta, err := os.ReadFile(fmt.Sprintf("%s.checkpoint", pth))
+	s.Assert().NoError(err)
+
+	lns := strings.Split(strings.TrimSuffix(string(dta), "\n"), "\n")
+	rgs := map[int]bool{}
+
+	for _, lne := range lns[1:] {
+		rng := map[string]int{}
+		s.Assert().NoError(json.Unmarshal([]byte(lne), &rng))
+		rgs[rng["start"]] = true
+	}
+
+	for off := 0; off < s.fal; off += 10 {
+		s.Assert().True(rgs[off], off)
+	}
+
+	s.mut.Lock()
+	s.rgs = 0
+	s.fal = 0
+	s.mut.Unlock()
+
+	s.Assert().NoError(s.clt.DownloadSnapshot(s.ctx, "enwiki_namespace_0", fle))
+
+	dta, err = os.ReadFile(pth)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.dta, dta)
+	s.Assert().Equal(len(s.dta)/10-len(rgs), s.rgs)
+}
+
+func TestCheckpoint(t *testing.T) {
+	dta := []byte(strings.Repeat("0123456789", 10))
+	lmd := time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)
+
+	for _, testCase := range []*checkpointTestSuite{
+		{
+			dta: dta,
+			lmd: lmd,
+		},
+		{
+			dta: dta,
+			lmd: lmd,
+			pre: 30,
+			ckp: []string{
+				`{"etag":"etag","last_modified":"2023-02-28T00:00:00Z","content_length":100}`,
+				`{"start":0,"end":10}`,
+				`{"start":10,"end":20}`,
+				`{"start":20,"end":30}`,
+				`{"start":30,`,
+			},
+		},
+		{
+			dta: dta,
+			lmd: lmd,
+			ckp: []string{
+				`{"etag":"changed","last_modified":"2023-02-28T00:00:00Z","content_length":100}`,
+				`{"start":0,"end":10}`,
+				`{"start":10,"end":20}`,
+			},
+		},
+		{
+			dta: dta,
+			lmd: lmd,
+			ckp: []string{
+				`{"etag":"etag","last_modified":"2023-02-28T00:00:00Z","content_length":100}`,
+				`{"start":0,"end":10}`,
+				`{"start":10,"end":20}`,
+			},
+		},
+		{
+			dta: dta,
+			lmd: lmd,
+			pre: 30,
+			trn: true,
+			fal: 60,
+			ckp: []string{
+				`{"etag":"etag","last_modified":"2023-02-28T00:00:00Z","content_length":100}`,
+				`{"start":0,"end":10}`,
+				`{"start":10,"end":20}`,
+				`{"start":20,"end":30}`,
+				`{"start":30,`,
+			},
+		},
+		{
+			dta: dta,
+			lmd: lmd,
+			stl: []byte(strings.Repeat("x", 150)),
+			ckp: []string{
+				`{"etag":"changed","last_modified":"2023-02-28T00:00:00Z","content_length":150}`,
+				`{"start":0,"end":10}`,
+			},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
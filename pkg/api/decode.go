@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/protsack-stephan/wme/pkg/filter"
+	"github.com/protsack-stephan/wme/pkg/ndjson"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// ReadStats holds the counters of a single read.
+type ReadStats struct {
+	Entries  int64 // Number of archive entries read, zero for streams.
+	Articles int64 // Number of articles passed to the callback.
+	Skipped  int64 // Number of lines skipped by the decode error handler.
+	Filtered int64 // Number of articles that didn't match ReadFilters.
+}
+
+func (s *ReadStats) article() {
+	atomic.AddInt64(&s.Articles, 1)
+}
+
+func (s *ReadStats) skip() {
+	atomic.AddInt64(&s.Skipped, 1)
+}
+
+func (s *ReadStats) filter() {
+	atomic.AddInt64(&s.Filtered, 1)
+}
+
+// ReadStatsCallback is a function that will be called with the counters at the end of each read, even if the read failed.
+type ReadStatsCallback func(sts *ReadStats)
+
+func (r ReadStatsCallback) report(sts *ReadStats) {
+	if r != nil {
+		r(sts)
+	}
+}
+
+// decodeResult is an outcome of decoding a single line, dre is the decode error that is not handled yet.
+type decodeResult struct {
+	art *schema.Article
+	dre *ndjson.DecodeError
+}
+
+// decodeJob is a single line waiting to be decoded.
+type decodeJob struct {
+	lne int
+	off int64
+	dta []byte
+	err error
+	res chan *decodeResult
+}
+
+// readLoopConcurrent decodes the lines on DecodeConcurrency workers.
+// Unless DecodeOrdered is set the callback is called concurrently from the workers,
+// otherwise results go through a reorder buffer and the callback is called sequentially in original order.
+// First error stops the workers and is returned.
+// Decode error handler is called the same way, from the workers or sequentially in original order if DecodeOrdered is set.
+func (c *Client) readLoopConcurrent(ctx context.Context, ent string, rdr io.Reader, sts *ReadStats, cbk ReadCallback) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var fer error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			fer = err
+			cancel()
+		})
+	}
+
+	dcs := c.DecodeConcurrency
+	fdt := c.readFieldTree()
+	jbs := make(chan *decodeJob, dcs)
+	ord := make(chan *decodeJob, dcs*2)
+	wgp := new(sync.WaitGroup)
+
+	for i := 0; i < dcs; i++ {
+		wgp.Add(1)
+
+		go func() {
+			defer wgp.Done()
+
+			for job := range jbs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				art := new(schema.Article)
+				err := job.err
+
+				if err == nil {
+					err = decodeArticle(job.dta, fdt, art)
+				}
+
+				res := new(decodeResult)
+
+				if err != nil {
+					res.dre = ndjson.NewDecodeError(ent, job.lne, job.off, job.dta, err)
+				} else if !filter.Match(art, c.ReadFilters...) {
+					sts.filter()
+				} else {
+					res.art = art
+				}
+
+				if c.DecodeOrdered {
+					job.res <- res
+					continue
+				}
+
+				if err := c.handleResult(res, sts, cbk); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	if c.DecodeOrdered {
+		wgp.Add(1)
+
+		go func() {
+			defer wgp.Done()
+
+			for job := range ord {
+				select {
+				case res := <-job.res:
+					if err := c.handleResult(res, sts, cbk); err != nil {
+						fail(err)
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	lrd := ndjson.NewReader(rdr, c.MaxLineSize)
+	var rer error
+
+feed:
+	for {
+		lne, err := lrd.ReadLine()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil && err != ndjson.ErrLineTooLong {
+			rer = err
+			break
+		}
+
+		// reader reuses the buffer, so the line needs to be copied
+		job := &decodeJob{
+			lne: lrd.Line(),
+			off: lrd.Offset(),
+			dta: append([]byte(nil), lne...),
+			err: err,
+			res: make(chan *decodeResult, 1),
+		}
+
+		if c.DecodeOrdered {
+			select {
+			case ord <- job:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+
+		select {
+		case jbs <- job:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(jbs)
+	close(ord)
+	wgp.Wait()
+
+	if fer != nil {
+		return fer
+	}
+
+	if rer != nil {
+		return rer
+	}
+
+	return ctx.Err()
+}
+
+// handleResult passes the decode error to the handler or the article to the callback.
+func (c *Client) handleResult(res *decodeResult, sts *ReadStats, cbk ReadCallback) error {
+	if res.dre != nil {
+		if err := c.DecodeErrorHandler.Handle(res.dre); err != nil {
+			return err
+		}
+
+		sts.skip()
+		return nil
+	}
+
+	if res.art == nil {
+		return nil
+	}
+
+	sts.article()
+	return cbk(res.art)
+}
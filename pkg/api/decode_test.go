@@ -0,0 +1,232 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/ndjson"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type decodeTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	clt api.API
+	arc []byte
+	nms []string
+	ord bool
+	fsn string
+	err error
+	mut sync.Mutex
+}
+
+func (s *decodeTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.DecodeConcurrency = 4
+		clt.DecodeOrdered = s.ord
+	})
+}
+
+func (s *decodeTestSuite) TestReadAll() {
+	nms := []string{}
+	err := s.clt.ReadAll(s.ctx, bytes.NewReader(s.arc), func(art *schema.Article) error {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+
+		if len(s.fsn) > 0 && art.Name == s.fsn {
+			return s.err
+		}
+
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	if s.err != nil {
+		s.Assert().ErrorIs(err, s.err)
+		return
+	}
+
+	s.Assert().NoError(err)
+
+	if s.ord {
+		s.Assert().Equal(s.nms, nms)
+	} else {
+		s.Assert().ElementsMatch(s.nms, nms)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	nms := []string{}
+	lns := []string{}
+
+	for i := 0; i < 100; i++ {
+		nms = append(nms, fmt.Sprintf("Article_%d", i))
+		lns = append(lns, fmt.Sprintf(`{"name":"Article_%d"}`, i))
+	}
+
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": lns[:50],
+		"enwiki_namespace_0_1.ndjson": lns[50:],
+	})
+
+	for _, testCase := range []*decodeTestSuite{
+		{
+			arc: arc,
+			nms: nms,
+			ord: true,
+		},
+		{
+			arc: arc,
+			nms: nms,
+		},
+		{
+			arc: arc,
+			ord: true,
+			fsn: "Article_42",
+			err: errors.New("stop reading"),
+		},
+		{
+			arc: arc,
+			fsn: "Article_42",
+			err: errors.New("stop reading"),
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type decodeErrorTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	clt api.API
+	arc []byte
+	dcs int
+	skp bool
+	nms []string
+	ers []string
+	dre []string
+	sts *api.ReadStats
+}
+
+func (s *decodeErrorTestSuite) SetupTest() {
+	s.dre = []string{}
+	s.ctx = context.Background()
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.MaxLineSize = 100
+		clt.DecodeConcurrency = s.dcs
+		clt.DecodeOrdered = true
+
+		if s.skp {
+			// the handler is not safe for concurrent use, it's called sequentially in ordered mode
+			clt.DecodeErrorHandler = func(err *ndjson.DecodeError) error {
+				s.Assert().NotEmpty(err.Raw)
+				s.Assert().LessOrEqual(len(err.Raw), ndjson.MaxRawSize)
+				s.dre = append(s.dre, fmt.Sprintf("%s:%d:%d", err.Entry, err.Line, err.Offset))
+				return nil
+			}
+		}
+
+		clt.Stats = func(sts *api.ReadStats) {
+			s.sts = sts
+		}
+	})
+}
+
+func (s *decodeErrorTestSuite) TestReadAll() {
+	nms := []string{}
+	err := s.clt.ReadAll(s.ctx, bytes.NewReader(s.arc), func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	if !s.skp {
+		dre := new(ndjson.DecodeError)
+		s.Assert().ErrorAs(err, &dre)
+		s.Assert().Equal("enwiki_namespace_0_0.ndjson", dre.Entry)
+		s.Assert().Equal(2, dre.Line)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.nms, nms)
+	s.Assert().Equal(s.ers, s.dre)
+	s.Assert().Equal(int64(len(s.nms)), s.sts.Articles)
+	s.Assert().Equal(int64(len(s.ers)), s.sts.Skipped)
+}
+
+func TestDecodeError(t *testing.T) {
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": {
+			`{"name":"Earth"}`,
+			`{"name":"Mars"`,
+			`{"name":"Venus"}`,
+		},
+		"enwiki_namespace_0_1.ndjson": {
+			`{"name":"Jupiter"}`,
+			`{"name":"` + strings.Repeat("a", 200) + `"}`,
+			`{"name":"Saturn"}`,
+		},
+	})
+	nms := []string{"Earth", "Venus", "Jupiter", "Saturn"}
+	ers := []string{"enwiki_namespace_0_0.ndjson:2:17", "enwiki_namespace_0_1.ndjson:2:19"}
+
+	// every other line is malformed
+	lns := []string{}
+	mnm := []string{}
+	mer := []string{}
+	off := 0
+
+	for i := 0; i < 200; i++ {
+		lne := fmt.Sprintf(`{"name":"Planet %d"}`, i)
+
+		if i%2 == 1 {
+			lne = fmt.Sprintf(`{"name":"Planet %d"`, i)
+			mer = append(mer, fmt.Sprintf("enwiki_namespace_0_0.ndjson:%d:%d", i+1, off))
+		} else {
+			mnm = append(mnm, fmt.Sprintf("Planet %d", i))
+		}
+
+		lns = append(lns, lne)
+		off += len(lne) + 1
+	}
+
+	for _, testCase := range []*decodeErrorTestSuite{
+		{
+			arc: arc,
+			nms: nms,
+			ers: ers,
+			skp: true,
+		},
+		{
+			arc: arc,
+			nms: nms,
+			ers: ers,
+			skp: true,
+			dcs: 4,
+		},
+		{
+			arc: createArchive(map[string][]string{"enwiki_namespace_0_0.ndjson": lns}),
+			nms: mnm,
+			ers: mer,
+			skp: true,
+			dcs: 8,
+		},
+		{
+			arc: arc,
+		},
+		{
+			arc: arc,
+			dcs: 4,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
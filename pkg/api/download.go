@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// minDownloadBufferSize is the smallest buffer a download worker is allowed to use.
+const minDownloadBufferSize = 32 * 1024
+
+// ErrContentChanged is returned when the content was replaced in the middle of the download or read.
+var ErrContentChanged = errors.New("content has changed")
+
+type chunk struct {
+	start int
+	end   int
+}
+
+// splitChunks splits the content into chunks of csz bytes, the last one can be smaller.
+func splitChunks(sze int, csz int) []*chunk {
+	cks := []*chunk{}
+
+	for i := 0; true; i++ {
+		cnk := &chunk{
+			start: i * csz,
+			end:   (i * csz) + csz,
+		}
+
+		if cnk.end > sze {
+			cnk.end = sze
+		}
+
+		cks = append(cks, cnk)
+
+		if cnk.end == sze {
+			break
+		}
+	}
+
+	return cks
+}
+
+// offsetWriter turns io.WriterAt into io.Writer that writes sequentially starting from the offset.
+type offsetWriter struct {
+	wat io.WriterAt
+	off int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.wat.WriteAt(p, o.off)
+	o.off += int64(n)
+	return n, err
+}
+
+// seekWriter is an io.WriterAt adapter for writers that can only seek,
+// writes are serialized because seek and write need to happen together.
+type seekWriter struct {
+	mut sync.Mutex
+	wsk io.WriteSeeker
+}
+
+func (s *seekWriter) WriteAt(p []byte, off int64) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if _, err := s.wsk.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return s.wsk.Write(p)
+}
+
+// newWriterAt returns the writer itself if it supports io.WriterAt, otherwise wraps it into the seekWriter.
+func newWriterAt(wsk io.WriteSeeker) io.WriterAt {
+	if wat, ok := wsk.(io.WriterAt); ok {
+		return wat
+	}
+
+	return &seekWriter{wsk: wsk}
+}
+
+// downloadBuffers splits the memory limit between the workers, returns the number of workers and size of the buffer for each of them.
+// Concurrency is lowered if the buffers would be smaller than minDownloadBufferSize.
+func (c *Client) downloadBuffers() (int, int) {
+	dcs := c.DownloadConcurrency
+
+	if dcs <= 0 {
+		dcs = 1
+	}
+
+	if c.DownloadMaxMemory <= 0 {
+		return dcs, minDownloadBufferSize
+	}
+
+	if c.DownloadMaxMemory/dcs < minDownloadBufferSize {
+		dcs = c.DownloadMaxMemory / minDownloadBufferSize
+
+		if dcs < 1 {
+			return 1, c.DownloadMaxMemory
+		}
+	}
+
+	return dcs, c.DownloadMaxMemory / dcs
+}
+
+// downloadChunk streams a single range of the entity directly into the writer.
+// If the connection breaks in the middle, retry continues from the last written byte.
+// Ranges are pinned to the ETag from the headers, so chunks of different versions are never mixed.
+func (c *Client) downloadChunk(ctx context.Context, pth string, hds *schema.Headers, wat io.WriterAt, cnk *chunk, buf []byte, prt *progressTracker) error {
+	off := cnk.start
+
+	return c.retry(ctx, func() error {
+		if off >= cnk.end {
+			return nil
+		}
+
+		hrq, err := c.newRequest(ctx, c.BaseUrl, http.MethodGet, pth, nil)
+
+		if err != nil {
+			return err
+		}
+
+		hrq.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, cnk.end-1))
+
+		// weak ETags never match, so those can't be used to pin the version
+		if len(hds.ETag) > 0 && !strings.HasPrefix(hds.ETag, "W/") {
+			hrq.Header.Set("If-Match", fmt.Sprintf(`"%s"`, hds.ETag))
+		}
+
+		res, err := c.do(hrq)
+
+		if aer, ok := apierror.As(err); ok && aer.StatusCode == http.StatusPreconditionFailed {
+			return fmt.Errorf("%w: etag of '%s' doesn't match '%s' anymore", ErrContentChanged, pth, hds.ETag)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		defer res.Body.Close()
+
+		// server is allowed to ignore the range, that is fine only if we need the whole content
+		if res.StatusCode != http.StatusPartialContent && !(res.StatusCode == http.StatusOK && off == 0 && cnk.end == hds.ContentLength) {
+			return fmt.Errorf("unexpected response status '%s' for range request", res.Status)
+		}
+
+		wrr := &progressWriter{
+			wrr: &offsetWriter{wat: wat, off: int64(off)},
+			prt: prt,
+		}
+		n, err := io.CopyBuffer(wrr, io.LimitReader(res.Body, int64(cnk.end-off)), buf)
+		off += int(n)
+
+		if err != nil {
+			return err
+		}
+
+		if off < cnk.end {
+			return io.ErrUnexpectedEOF
+		}
+
+		return nil
+	})
+}
+
+func (c *Client) downloadEntity(ctx context.Context, pth string, wat io.WriterAt) error {
+	hds, err := c.headEntity(ctx, pth)
+
+	if err != nil {
+		return err
+	}
+
+	// checkpoint and verification need to look at the original writer
+	var wrr interface{} = wat
+
+	if swr, ok := wat.(*seekWriter); ok {
+		wrr = swr.wsk
+	}
+
+	csz := c.DownloadChunkSize
+
+	if hds.ContentLength < c.DownloadMinChunkSize {
+		csz = c.DownloadMinChunkSize
+	}
+
+	cks := splitChunks(hds.ContentLength, csz)
+	ctl := len(cks)
+	var ckp *checkpoint
+
+	if nmr, ok := wrr.(interface{ Name() string }); ok && c.DownloadCheckpoint {
+		ckp, err = openCheckpoint(fmt.Sprintf("%s.checkpoint", nmr.Name()), hds, wrr)
+
+		if err != nil {
+			return err
+		}
+
+		defer ckp.Close()
+		pnd := []*chunk{}
+
+		for _, cnk := range cks {
+			if !ckp.isDone(cnk.start, cnk.end) {
+				pnd = append(pnd, cnk)
+			}
+		}
+
+		cks = pnd
+	}
+
+	prt := c.newProgressTracker(pth, int64(hds.ContentLength), ctl)
+	pbs := 0
+
+	for _, cnk := range cks {
+		pbs += cnk.end - cnk.start
+	}
+
+	prt.skip(int64(hds.ContentLength-pbs), ctl-len(cks))
+
+	dcs, bsz := c.downloadBuffers()
+
+	if dcs > len(cks) {
+		dcs = len(cks)
+	}
+
+	// first failed chunk cancels the rest of the workers
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var fer error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			fer = err
+			cancel()
+		})
+	}
+
+	cds := make(chan *chunk)
+	wgp := new(sync.WaitGroup)
+
+	for i := 0; i < dcs; i++ {
+		wgp.Add(1)
+
+		go func() {
+			defer wgp.Done()
+			buf := make([]byte, bsz)
+
+			for cnk := range cds {
+				if err := c.downloadChunk(ctx, pth, hds, wat, cnk, buf, prt); err != nil {
+					fail(err)
+					return
+				}
+
+				if ckp != nil {
+					if err := ckp.done(cnk.start, cnk.end); err != nil {
+						fail(err)
+						return
+					}
+				}
+
+				prt.chunk()
+			}
+		}()
+	}
+
+feed:
+	for _, cnk := range cks {
+		select {
+		case cds <- cnk:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(cds)
+	wgp.Wait()
+
+	if fer != nil {
+		return fer
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if ckp != nil {
+		if err := ckp.remove(); err != nil {
+			return err
+		}
+	}
+
+	if !c.DownloadVerify {
+		return nil
+	}
+
+	rat, ok := wrr.(io.ReaderAt)
+
+	if !ok {
+		return errors.New("can't verify the download, writer doesn't implement io.ReaderAt")
+	}
+
+	// content is fully downloaded, but caller needs to know that it wasn't verified
+	return verify(rat, int64(hds.ContentLength), hds)
+}
@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/stretchr/testify/suite"
+)
+
+// writerAtBuffer is an in memory io.WriterAt.
+type writerAtBuffer struct {
+	mut sync.Mutex
+	dta []byte
+}
+
+func (b *writerAtBuffer) WriteAt(p []byte, off int64) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if end := int(off) + len(p); end > len(b.dta) {
+		b.dta = append(b.dta, make([]byte, end-len(b.dta))...)
+	}
+
+	return copy(b.dta[off:], p), nil
+}
+
+// writeSeeker hides everything except io.WriteSeeker of the underlying file.
+type writeSeeker struct {
+	wsk io.WriteSeeker
+}
+
+func (w *writeSeeker) Write(p []byte) (int, error) {
+	return w.wsk.Write(p)
+}
+
+func (w *writeSeeker) Seek(off int64, whn int) (int64, error) {
+	return w.wsk.Seek(off, whn)
+}
+
+type downloadTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	clt api.API
+	dta []byte
+	mmr int
+	fst int
+	nrg bool
+	err bool
+}
+
+func (s *downloadTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fst > 0 && strings.HasPrefix(r.Header.Get("Range"), fmt.Sprintf("bytes=%d-", s.fst)) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if s.nrg {
+			r.Header.Del("Range")
+		}
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.dta))
+	}))
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.BaseUrl = fmt.Sprintf("%s/", s.srv.URL)
+		clt.DownloadChunkSize = 10
+		clt.DownloadMinChunkSize = 10
+		clt.DownloadConcurrency = 3
+		clt.DownloadMaxMemory = s.mmr
+	})
+}
+
+func (s *downloadTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *downloadTestSuite) TestDownloadSnapshotAt() {
+	wat := &writerAtBuffer{dta: []byte{}}
+	err := s.clt.(api.SnapshotDownloaderAt).DownloadSnapshotAt(s.ctx, "enwiki_namespace_0", wat)
+
+	if s.err {
+		s.Assert().Error(err)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.dta, wat.dta)
+}
+
+func (s *downloadTestSuite) TestDownloadSnapshot() {
+	pth := filepath.Join(s.T().TempDir(), "enwiki_namespace_0.tar.gz")
+	fle, err := os.Create(pth)
+	s.Assert().NoError(err)
+	defer fle.Close()
+
+	err = s.clt.DownloadSnapshot(s.ctx, "enwiki_namespace_0", &writeSeeker{wsk: fle})
+
+	if s.err {
+		s.Assert().Error(err)
+		return
+	}
+
+	s.Assert().NoError(err)
+
+	dta, err := os.ReadFile(pth)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.dta, dta)
+}
+
+func TestDownload(t *testing.T) {
+	dta := []byte(strings.Repeat("0123456789", 9) + "01234")
+
+	for _, testCase := range []*downloadTestSuite{
+		{
+			dta: dta,
+		},
+		{
+			dta: dta,
+			mmr: 100,
+		},
+		{
+			dta: []byte{},
+		},
+		{
+			dta: dta,
+			fst: 50,
+			err: true,
+		},
+		{
+			dta: dta,
+			nrg: true,
+			err: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
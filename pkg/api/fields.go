@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/protsack-stephan/wme/pkg/filter"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// errInvalidJSON is returned when the projection can't scan the line, in that case the line is fully decoded instead.
+var errInvalidJSON = errors.New("invalid JSON")
+
+// fieldTree is a parsed list of dotted field paths, for example `version.identifier` or `event.*`.
+type fieldTree struct {
+	all bool
+	fds map[string]*fieldTree
+}
+
+// readFieldTree returns the projection for ReadFields, fields used by ReadFilters are decoded as well.
+func (c *Client) readFieldTree() *fieldTree {
+	if len(c.ReadFields) == 0 {
+		return nil
+	}
+
+	return newFieldTree(append(filter.Fields(c.ReadFilters...), c.ReadFields...))
+}
+
+// newFieldTree parses the fields, returns nil if there's nothing to project (everything should be decoded).
+func newFieldTree(fds []string) *fieldTree {
+	if len(fds) == 0 {
+		return nil
+	}
+
+	rot := &fieldTree{fds: map[string]*fieldTree{}}
+
+	for _, fld := range fds {
+		nde := rot
+
+		for _, key := range strings.Split(fld, ".") {
+			if key == "*" || nde.all {
+				break
+			}
+
+			chd, ok := nde.fds[key]
+
+			if !ok {
+				chd = &fieldTree{fds: map[string]*fieldTree{}}
+				nde.fds[key] = chd
+			}
+
+			nde = chd
+		}
+
+		nde.all = true
+	}
+
+	if rot.all {
+		return nil
+	}
+
+	return rot
+}
+
+// decodeArticle decodes only the fields from the tree, the rest of the line is skipped without being decoded.
+func decodeArticle(dta []byte, fdt *fieldTree, art *schema.Article) error {
+	if fdt == nil {
+		return json.Unmarshal(dta, art)
+	}
+
+	out, err := projectLine(dta, fdt)
+
+	// fallback to the full decoding, it will also describe what's wrong with the line
+	if err != nil {
+		return json.Unmarshal(dta, art)
+	}
+
+	return json.Unmarshal(out, art)
+}
+
+// projectLine returns a copy of the JSON object that contains only the fields from the tree.
+func projectLine(dta []byte, fdt *fieldTree) ([]byte, error) {
+	i := skipSpace(dta, 0)
+
+	if i >= len(dta) || dta[i] != '{' {
+		return nil, errInvalidJSON
+	}
+
+	i, out, err := projectObject(dta, i, fdt, make([]byte, 0, 256))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if skipSpace(dta, i) != len(dta) {
+		return nil, errInvalidJSON
+	}
+
+	return out, nil
+}
+
+func projectValue(dta []byte, i int, fdt *fieldTree, out []byte) (int, []byte, error) {
+	if i >= len(dta) {
+		return i, out, errInvalidJSON
+	}
+
+	switch dta[i] {
+	case '{':
+		return projectObject(dta, i, fdt, out)
+	case '[':
+		return projectArray(dta, i, fdt, out)
+	}
+
+	end, err := skipValue(dta, i)
+
+	if err != nil {
+		return end, out, err
+	}
+
+	return end, append(out, dta[i:end]...), nil
+}
+
+func projectObject(dta []byte, i int, fdt *fieldTree, out []byte) (int, []byte, error) {
+	out = append(out, '{')
+	i = skipSpace(dta, i+1)
+	fst := true
+
+	if i < len(dta) && dta[i] == '}' {
+		return i + 1, append(out, '}'), nil
+	}
+
+	for {
+		if i >= len(dta) || dta[i] != '"' {
+			return i, out, errInvalidJSON
+		}
+
+		kst := i
+		ken, err := skipString(dta, i)
+
+		if err != nil {
+			return ken, out, err
+		}
+
+		i = skipSpace(dta, ken)
+
+		if i >= len(dta) || dta[i] != ':' {
+			return i, out, errInvalidJSON
+		}
+
+		i = skipSpace(dta, i+1)
+		key, err := unquoteKey(dta[kst:ken])
+
+		if err != nil {
+			return i, out, err
+		}
+
+		chd := fdt.fds[key]
+
+		if chd == nil {
+			i, err = skipValue(dta, i)
+		} else {
+			if !fst {
+				out = append(out, ',')
+			}
+
+			fst = false
+			out = append(append(out, dta[kst:ken]...), ':')
+
+			if chd.all {
+				end, err := skipValue(dta, i)
+
+				if err != nil {
+					return end, out, err
+				}
+
+				out = append(out, dta[i:end]...)
+				i = end
+			} else {
+				i, out, err = projectValue(dta, i, chd, out)
+			}
+		}
+
+		if err != nil {
+			return i, out, err
+		}
+
+		i = skipSpace(dta, i)
+
+		if i >= len(dta) {
+			return i, out, errInvalidJSON
+		}
+
+		switch dta[i] {
+		case ',':
+			i = skipSpace(dta, i+1)
+		case '}':
+			return i + 1, append(out, '}'), nil
+		default:
+			return i, out, errInvalidJSON
+		}
+	}
+}
+
+// projectArray applies the same projection to every element, so `categories.name` works for the lists of objects.
+func projectArray(dta []byte, i int, fdt *fieldTree, out []byte) (int, []byte, error) {
+	out = append(out, '[')
+	i = skipSpace(dta, i+1)
+
+	if i < len(dta) && dta[i] == ']' {
+		return i + 1, append(out, ']'), nil
+	}
+
+	for {
+		var err error
+		i, out, err = projectValue(dta, i, fdt, out)
+
+		if err != nil {
+			return i, out, err
+		}
+
+		i = skipSpace(dta, i)
+
+		if i >= len(dta) {
+			return i, out, errInvalidJSON
+		}
+
+		switch dta[i] {
+		case ',':
+			out = append(out, ',')
+			i = skipSpace(dta, i+1)
+		case ']':
+			return i + 1, append(out, ']'), nil
+		default:
+			return i, out, errInvalidJSON
+		}
+	}
+}
+
+func skipSpace(dta []byte, i int) int {
+	for i < len(dta) && (dta[i] == ' ' || dta[i] == '\t' || dta[i] == '\r' || dta[i] == '\n') {
+		i++
+	}
+
+	return i
+}
+
+// skipString returns the position right after the closing quote.
+func skipString(dta []byte, i int) (int, error) {
+	for i++; i < len(dta); i++ {
+		switch dta[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+
+	return i, errInvalidJSON
+}
+
+// unquoteKey returns the object key without the quotes, escaped keys (for example `"\u006eame"`) are decoded.
+func unquoteKey(dta []byte) (string, error) {
+	if bytes.IndexByte(dta, '\\') == -1 {
+		return string(dta[1 : len(dta)-1]), nil
+	}
+
+	key := ""
+
+	if err := json.Unmarshal(dta, &key); err != nil {
+		return "", errInvalidJSON
+	}
+
+	return key, nil
+}
+
+// skipValue returns the position right after the value that starts at i.
+// The skipped span is validated, so malformed lines are not hidden by the projection.
+func skipValue(dta []byte, i int) (int, error) {
+	end, err := scanValue(dta, i)
+
+	if err != nil {
+		return end, err
+	}
+
+	if !json.Valid(dta[i:end]) {
+		return end, errInvalidJSON
+	}
+
+	return end, nil
+}
+
+// scanValue finds the end of the value that starts at i without validating it.
+func scanValue(dta []byte, i int) (int, error) {
+	if i >= len(dta) {
+		return i, errInvalidJSON
+	}
+
+	switch dta[i] {
+	case '"':
+		return skipString(dta, i)
+	case '{', '[':
+		dpt := 0
+
+		for ; i < len(dta); i++ {
+			switch dta[i] {
+			case '"':
+				end, err := skipString(dta, i)
+
+				if err != nil {
+					return end, err
+				}
+
+				i = end - 1
+			case '{', '[':
+				dpt++
+			case '}', ']':
+				dpt--
+
+				if dpt == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+
+		return i, errInvalidJSON
+	}
+
+	end := i
+
+	for end < len(dta) && !strings.ContainsRune(",}] \t\r\n", rune(dta[end])) {
+		end++
+	}
+
+	if end == i {
+		return end, errInvalidJSON
+	}
+
+	return end, nil
+}
@@ -0,0 +1,118 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+const fieldsTestArticle = `{
+	"name": "Earth",
+	"identifier": 9228,
+	"abstract": "Earth is the third planet from the Sun.",
+	"version": {"identifier": 1138817270, "comment": "typo, \"fix\" {", "editor": {"identifier": 1, "name": "Bot"}},
+	"categories": [{"name": "Category:Earth", "url": "https://en.wikipedia.org/wiki/Category:Earth"}, {"name": "Category:Planets"}],
+	"article_body": {"html": "<p>Earth [1]</p>", "wikitext": "'''Earth''' {{Planet}}"},
+	"event": {"identifier": "e6a1", "type": "update", "partition": 7, "offset": 42},
+	"is_part_of": {"identifier": "enwiki"}
+}`
+
+type fieldsTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	clt api.API
+	arc []byte
+	fds []string
+	art string
+	err bool
+}
+
+func (s *fieldsTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.ReadFields = s.fds
+	})
+}
+
+func (s *fieldsTestSuite) TestReadAll() {
+	ats := []*schema.Article{}
+	err := s.clt.ReadAll(s.ctx, bytes.NewReader(s.arc), func(art *schema.Article) error {
+		ats = append(ats, art)
+		return nil
+	})
+
+	if s.err {
+		s.Assert().Error(err)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Len(ats, 1)
+
+	art := new(schema.Article)
+	s.Assert().NoError(json.Unmarshal([]byte(s.art), art))
+	s.Assert().Equal(art, ats[0])
+}
+
+func TestFields(t *testing.T) {
+	lne := new(bytes.Buffer)
+	_ = json.Compact(lne, []byte(fieldsTestArticle))
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": {lne.String()},
+	})
+
+	for _, testCase := range []*fieldsTestSuite{
+		{
+			arc: arc,
+			art: fieldsTestArticle,
+		},
+		{
+			arc: arc,
+			fds: []string{"name", "version.identifier", "event.*", "categories.name"},
+			art: `{
+				"name": "Earth",
+				"version": {"identifier": 1138817270},
+				"categories": [{"name": "Category:Earth"}, {"name": "Category:Planets"}],
+				"event": {"identifier": "e6a1", "type": "update", "partition": 7, "offset": 42}
+			}`,
+		},
+		{
+			arc: arc,
+			fds: []string{"identifier", "version", "is_part_of.identifier", "article_body.unknown"},
+			art: `{
+				"identifier": 9228,
+				"version": {"identifier": 1138817270, "comment": "typo, \"fix\" {", "editor": {"identifier": 1, "name": "Bot"}},
+				"article_body": {},
+				"is_part_of": {"identifier": "enwiki"}
+			}`,
+		},
+		{
+			arc: createArchive(map[string][]string{
+				"enwiki_namespace_0_0.ndjson": {`{"name":"Earth","version":{"identifier":1`},
+			}),
+			fds: []string{"name"},
+			err: true,
+		},
+		{
+			arc: createArchive(map[string][]string{
+				"enwiki_namespace_0_0.ndjson": {`{"name":"Earth","article_body":{"html":tru,"wikitext":[1 2]}}`},
+			}),
+			fds: []string{"name"},
+			err: true,
+		},
+		{
+			arc: createArchive(map[string][]string{
+				"enwiki_namespace_0_0.ndjson": {`{"\u006eame":"Earth","ver\"sion":{"identifier":1},"identifier":9228}`},
+			}),
+			fds: []string{"name"},
+			art: `{"name": "Earth"}`,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
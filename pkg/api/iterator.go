@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// Iterator is a pull based reader of articles, an alternative to the ReadCallback.
+// Reading happens in the background and stops when the consumer stops pulling,
+// so Close needs to be called once you are done with the iterator.
+//
+//	itr := clt.IterateSnapshot(ctx, "enwiki_namespace_0")
+//	defer itr.Close()
+//
+//	for itr.Next() {
+//		fmt.Println(itr.Article().Name)
+//	}
+//
+//	if err := itr.Err(); err != nil {
+//		log.Panic(err)
+//	}
+type Iterator struct {
+	cancel context.CancelFunc
+	ats    chan *schema.Article
+	art    *schema.Article
+	rer    error
+	err    error
+	cls    bool
+}
+
+// newIterator runs the callback based reader in the background, handing over the articles one by one.
+func newIterator(ctx context.Context, fnc func(ctx context.Context, cbk ReadCallback) error) *Iterator {
+	ctx, cancel := context.WithCancel(ctx)
+	itr := &Iterator{
+		cancel: cancel,
+		ats:    make(chan *schema.Article),
+	}
+
+	go func() {
+		defer close(itr.ats)
+
+		itr.rer = fnc(ctx, func(art *schema.Article) error {
+			select {
+			case itr.ats <- art:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	return itr
+}
+
+// Next advances the iterator to the next article, returns false once there's nothing left or the reading failed.
+func (i *Iterator) Next() bool {
+	if i.cls {
+		return false
+	}
+
+	art, ok := <-i.ats
+
+	if !ok {
+		i.art = nil
+		i.err = i.rer
+		return false
+	}
+
+	i.art = art
+	return true
+}
+
+// Article returns the current article, should be called after Next returns true.
+func (i *Iterator) Article() *schema.Article {
+	return i.art
+}
+
+// Err returns the error that stopped the iteration, nil if all of the articles were read.
+func (i *Iterator) Err() error {
+	return i.err
+}
+
+// Close stops the reading and releases the resources, safe to call multiple times.
+func (i *Iterator) Close() error {
+	if i.cls {
+		return nil
+	}
+
+	i.cls = true
+	i.cancel()
+
+	// wait for the reader to exit
+	for range i.ats {
+	}
+
+	return nil
+}
+
+// All returns a sequence of the articles that can be used with range-over-func (Go 1.23 and above).
+// Error, if any, is yielded as the last element, iterator is closed when the loop ends.
+//
+//	for art, err := range clt.IterateSnapshot(ctx, "enwiki_namespace_0").All() {
+//		if err != nil {
+//			log.Panic(err)
+//		}
+//
+//		fmt.Println(art.Name)
+//	}
+func (i *Iterator) All() func(yield func(*schema.Article, error) bool) {
+	return func(yield func(*schema.Article, error) bool) {
+		defer i.Close()
+
+		for i.Next() {
+			if !yield(i.Article(), nil) {
+				return
+			}
+		}
+
+		if err := i.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
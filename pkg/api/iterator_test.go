@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type iteratorTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	clt api.API
+	arc []byte
+	nms []string
+	brk int
+	err bool
+}
+
+func (s *iteratorTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.clt = api.NewClient()
+}
+
+func (s *iteratorTestSuite) TestIterateAll() {
+	itr := s.clt.(api.AllIterator).IterateAll(s.ctx, bytes.NewReader(s.arc))
+	defer itr.Close()
+
+	nms := []string{}
+
+	for itr.Next() {
+		if len(nms) == s.brk && s.brk > 0 {
+			s.Assert().NoError(itr.Close())
+			break
+		}
+
+		nms = append(nms, itr.Article().Name)
+	}
+
+	s.Assert().False(itr.Next())
+
+	if s.err {
+		s.Assert().Error(itr.Err())
+		return
+	}
+
+	s.Assert().NoError(itr.Err())
+
+	if s.brk > 0 {
+		s.Assert().Equal(s.nms[:s.brk], nms)
+	} else {
+		s.Assert().Equal(s.nms, nms)
+	}
+}
+
+func (s *iteratorTestSuite) TestAll() {
+	nms := []string{}
+	var ier error
+
+	s.clt.(api.AllIterator).IterateAll(s.ctx, bytes.NewReader(s.arc)).All()(func(art *schema.Article, err error) bool {
+		if err != nil {
+			ier = err
+			return false
+		}
+
+		if len(nms) == s.brk && s.brk > 0 {
+			return false
+		}
+
+		nms = append(nms, art.Name)
+		return true
+	})
+
+	if s.err {
+		s.Assert().Error(ier)
+		return
+	}
+
+	s.Assert().NoError(ier)
+
+	if s.brk > 0 {
+		s.Assert().Equal(s.nms[:s.brk], nms)
+	} else {
+		s.Assert().Equal(s.nms, nms)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	nms := []string{}
+	lns := []string{}
+
+	for i := 0; i < 20; i++ {
+		nms = append(nms, fmt.Sprintf("Article_%d", i))
+		lns = append(lns, fmt.Sprintf(`{"name":"Article_%d"}`, i))
+	}
+
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": lns,
+	})
+
+	for _, testCase := range []*iteratorTestSuite{
+		{
+			arc: arc,
+			nms: nms,
+		},
+		{
+			arc: arc,
+			nms: nms,
+			brk: 3,
+		},
+		{
+			arc: []byte("not an archive"),
+			err: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Progress is a snapshot of download (or streaming read) progress.
+type Progress struct {
+	Path        string        // Path of the entity, for example `snapshots/enwiki_namespace_0/download`.
+	BytesDone   int64         // Number of bytes downloaded so far (compressed bytes consumed for reads).
+	BytesTotal  int64         // Total size of the entity, zero if unknown.
+	ChunksDone  int           // Number of completed chunks, always zero for reads.
+	ChunksTotal int           // Total number of chunks, always zero for reads.
+	Elapsed     time.Duration // Time passed since the start.
+	Throughput  float64       // Average number of bytes per second since the start.
+	ETA         time.Duration // Estimated time left, zero if unknown.
+}
+
+// ProgressCallback is a function that will be called with the progress of downloads and reads.
+// Calls are serialized, so the callback doesn't have to be safe for concurrent use, but it should not block for long.
+type ProgressCallback func(prg *Progress)
+
+// ProgressChannel returns a ProgressCallback that sends progress updates to the channel.
+// Updates are dropped if the channel is full, so slow consumer doesn't slow down the download.
+func ProgressChannel(chn chan<- *Progress) ProgressCallback {
+	return func(prg *Progress) {
+		select {
+		case chn <- prg:
+		default:
+		}
+	}
+}
+
+// WithProgress is a functional option for NewClient that enables progress reporting.
+func WithProgress(cbk ProgressCallback) func(clt *Client) {
+	return func(clt *Client) {
+		clt.Progress = cbk
+	}
+}
+
+// progressTracker aggregates the progress from multiple workers and reports it at most once per interval.
+// Chunk completion and the end of the transfer are always reported.
+type progressTracker struct {
+	mut sync.Mutex
+	cbk ProgressCallback
+	itv time.Duration
+	stt time.Time
+	lst time.Time
+	ini int64
+	prg Progress
+}
+
+func (c *Client) newProgressTracker(pth string, btl int64, ctl int) *progressTracker {
+	if c.Progress == nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	return &progressTracker{
+		cbk: c.Progress,
+		itv: c.ProgressInterval,
+		stt: now,
+		lst: now,
+		prg: Progress{
+			Path:        pth,
+			BytesTotal:  btl,
+			ChunksTotal: ctl,
+		},
+	}
+}
+
+// skip accounts for the chunks that were completed earlier (resumed downloads).
+func (p *progressTracker) skip(bts int64, cks int) {
+	if p == nil {
+		return
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.ini += bts
+	p.prg.BytesDone += bts
+	p.prg.ChunksDone += cks
+}
+
+func (p *progressTracker) add(n int64) {
+	if p == nil || n <= 0 {
+		return
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.prg.BytesDone += n
+
+	if time.Since(p.lst) >= p.itv {
+		p.report()
+	}
+}
+
+func (p *progressTracker) chunk() {
+	if p == nil {
+		return
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.prg.ChunksDone++
+	p.report()
+}
+
+func (p *progressTracker) finish() {
+	if p == nil {
+		return
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.report()
+}
+
+// report needs to be called under the lock.
+func (p *progressTracker) report() {
+	now := time.Now()
+	p.lst = now
+
+	prg := p.prg
+	prg.Elapsed = now.Sub(p.stt)
+
+	// only bytes transferred by this process are used for the estimates
+	if sec := prg.Elapsed.Seconds(); sec > 0 {
+		prg.Throughput = float64(prg.BytesDone-p.ini) / sec
+	}
+
+	if prg.Throughput > 0 && prg.BytesTotal > prg.BytesDone {
+		prg.ETA = time.Duration(float64(prg.BytesTotal-prg.BytesDone) / prg.Throughput * float64(time.Second))
+	}
+
+	p.cbk(&prg)
+}
+
+// progressWriter counts the bytes written through it.
+type progressWriter struct {
+	wrr io.Writer
+	prt *progressTracker
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.wrr.Write(b)
+	p.prt.add(int64(n))
+	return n, err
+}
+
+// progressReader counts the bytes read through it.
+type progressReader struct {
+	rdr io.Reader
+	prt *progressTracker
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.rdr.Read(b)
+	p.prt.add(int64(n))
+	return n, err
+}
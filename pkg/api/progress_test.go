@@ -0,0 +1,114 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type progressTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	clt api.API
+	dta []byte
+	prs []*api.Progress
+	mut sync.Mutex
+}
+
+func (s *progressTestSuite) SetupTest() {
+	s.prs = []*api.Progress{}
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.dta))
+	}))
+	s.clt = api.NewClient(
+		func(clt *api.Client) {
+			clt.BaseUrl = fmt.Sprintf("%s/", s.srv.URL)
+			clt.DownloadChunkSize = 100
+			clt.DownloadMinChunkSize = 100
+			clt.DownloadConcurrency = 2
+			clt.ProgressInterval = 0
+		},
+		api.WithProgress(func(prg *api.Progress) {
+			s.mut.Lock()
+			defer s.mut.Unlock()
+
+			s.prs = append(s.prs, prg)
+		}),
+	)
+}
+
+func (s *progressTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *progressTestSuite) TestDownloadSnapshotAt() {
+	s.Assert().NoError(s.clt.(api.SnapshotDownloaderAt).DownloadSnapshotAt(s.ctx, "enwiki_namespace_0", new(writerAtBuffer)))
+	s.Assert().NotEmpty(s.prs)
+
+	ctl := (len(s.dta) + 99) / 100
+	lst := s.prs[len(s.prs)-1]
+	s.Assert().Equal("snapshots/enwiki_namespace_0/download", lst.Path)
+	s.Assert().Equal(int64(len(s.dta)), lst.BytesDone)
+	s.Assert().Equal(int64(len(s.dta)), lst.BytesTotal)
+	s.Assert().Equal(ctl, lst.ChunksDone)
+	s.Assert().Equal(ctl, lst.ChunksTotal)
+	s.Assert().Zero(lst.ETA)
+
+	for i := 1; i < len(s.prs); i++ {
+		s.Assert().GreaterOrEqual(s.prs[i].BytesDone, s.prs[i-1].BytesDone)
+	}
+}
+
+func (s *progressTestSuite) TestReadSnapshot() {
+	s.Assert().NoError(s.clt.ReadSnapshot(s.ctx, "enwiki_namespace_0", func(art *schema.Article) error {
+		return nil
+	}))
+	s.Assert().NotEmpty(s.prs)
+
+	lst := s.prs[len(s.prs)-1]
+	s.Assert().Equal(int64(len(s.dta)), lst.BytesDone)
+	s.Assert().Equal(int64(len(s.dta)), lst.BytesTotal)
+	s.Assert().Zero(lst.ChunksTotal)
+}
+
+func TestProgress(t *testing.T) {
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": {
+			`{"name":"Earth","article_body":{"wikitext":"` + strings.Repeat("Earth is the third planet from the Sun. ", 20) + `"}}`,
+			`{"name":"Mars","article_body":{"wikitext":"` + strings.Repeat("Mars is the fourth planet from the Sun. ", 20) + `"}}`,
+		},
+	})
+
+	for _, testCase := range []*progressTestSuite{
+		{
+			dta: arc,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+func TestProgressChannel(t *testing.T) {
+	chn := make(chan *api.Progress, 1)
+	cbk := api.ProgressChannel(chn)
+
+	// second update is dropped, because the channel is full
+	cbk(&api.Progress{BytesDone: 1})
+	cbk(&api.Progress{BytesDone: 2})
+
+	assert.Len(t, chn, 1)
+	assert.Equal(t, int64(1), (<-chn).BytesDone)
+}
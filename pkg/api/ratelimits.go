@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/protsack-stephan/wme/pkg/ratelimit"
+)
+
+// WithRateLimits is a functional option for NewClient that enables client side rate limiting.
+// Limits are shared between all the goroutines using the client.
+func WithRateLimits(rls *RateLimits) func(clt *Client) {
+	return func(clt *Client) {
+		clt.RateLimits = rls
+	}
+}
+
+// RateLimits holds rate limiters per endpoint group, nil limiter means no limit for the group.
+// Applies to every request sent by the client, regardless of the base URL it's sent to.
+type RateLimits struct {
+	Metadata  *ratelimit.Limiter // Codes, languages, projects, namespaces, batches and snapshots metadata.
+	Articles  *ratelimit.Limiter // On-demand lookups of articles and things.
+	Downloads *ratelimit.Limiter // Downloads and reads of batches and snapshots, every chunk counts as a request.
+	Streaming *ratelimit.Limiter // Connections to the realtime streams, every reconnect counts as a request.
+}
+
+func (r *RateLimits) limiter(pth string) *ratelimit.Limiter {
+	switch {
+	case pth == "articles":
+		return r.Streaming
+	case strings.HasSuffix(pth, "/download"):
+		return r.Downloads
+	case strings.HasPrefix(pth, "articles/"), strings.HasPrefix(pth, "things/"):
+		return r.Articles
+	default:
+		return r.Metadata
+	}
+}
+
+func (c *Client) wait(ctx context.Context, pth string) error {
+	if c.RateLimits == nil {
+		return nil
+	}
+
+	return c.RateLimits.limiter(pth).Wait(ctx)
+}
@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/ratelimit"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type rateLimitsTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	clt api.API
+	rls *api.RateLimits
+	rqs int
+	cns int
+	min time.Duration
+	max time.Duration
+	lmt string
+	rmn string
+}
+
+func (s *rateLimitsTestSuite) SetupSuite() {
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.lmt) > 0 {
+			w.Header().Set("X-RateLimit-Limit", s.lmt)
+			w.Header().Set("X-RateLimit-Remaining", s.rmn)
+		}
+
+		if r.URL.Path == "/v2/articles" {
+			_, _ = w.Write([]byte(`{"name":"Earth"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`[{"name":"Earth"}]`))
+	}))
+	s.clt = api.NewClient(
+		func(clt *api.Client) {
+			clt.BaseUrl = fmt.Sprintf("%s/", s.srv.URL)
+			clt.RealtimeURL = fmt.Sprintf("%s/", s.srv.URL)
+		},
+		api.WithRateLimits(s.rls),
+	)
+}
+
+func (s *rateLimitsTestSuite) TearDownSuite() {
+	s.srv.Close()
+}
+
+func (s *rateLimitsTestSuite) TestGetArticles() {
+	if s.rqs == 0 {
+		s.T().Skip("only for lookups")
+	}
+
+	stt := time.Now()
+
+	for i := 0; i < s.rqs; i++ {
+		_, err := s.clt.GetArticles(s.ctx, "Earth", nil)
+		s.Assert().NoError(err)
+	}
+
+	s.Assert().GreaterOrEqual(time.Since(stt), s.min)
+	s.Assert().Less(time.Since(stt), s.max)
+
+	qgt, ok := s.clt.(api.QuotaGetter)
+	s.Assert().True(ok)
+
+	if len(s.lmt) > 0 {
+		s.Assert().NotNil(qgt.GetQuota())
+		s.Assert().Equal(s.lmt, fmt.Sprint(qgt.GetQuota().Limit))
+		s.Assert().Equal(s.rmn, fmt.Sprint(qgt.GetQuota().Remaining))
+	} else {
+		s.Assert().Nil(qgt.GetQuota())
+	}
+}
+
+func (s *rateLimitsTestSuite) TestStreamArticles() {
+	if s.cns == 0 {
+		s.T().Skip("only for streams")
+	}
+
+	stt := time.Now()
+
+	for i := 0; i < s.cns; i++ {
+		s.Assert().NoError(s.clt.StreamArticles(s.ctx, nil, func(art *schema.Article) error {
+			return nil
+		}))
+	}
+
+	s.Assert().GreaterOrEqual(time.Since(stt), s.min)
+	s.Assert().Less(time.Since(stt), s.max)
+}
+
+func TestRateLimits(t *testing.T) {
+	for _, testCase := range []*rateLimitsTestSuite{
+		{
+			rls: &api.RateLimits{
+				Articles: ratelimit.NewLimiter(50, 1),
+			},
+			rqs: 4,
+			min: time.Millisecond * 55,
+			max: time.Second,
+			lmt: "100",
+			rmn: "96",
+		},
+		{
+			rls: &api.RateLimits{
+				Metadata: ratelimit.NewLimiter(1, 1),
+			},
+			rqs: 4,
+			max: time.Millisecond * 500,
+		},
+		{
+			rls: &api.RateLimits{
+				Streaming: ratelimit.NewLimiter(50, 1),
+			},
+			cns: 4,
+			min: time.Millisecond * 55,
+			max: time.Second,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
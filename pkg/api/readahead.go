@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// chunkBuffer is an io.WriterAt that stores a single chunk in memory.
+type chunkBuffer struct {
+	off int
+	dta []byte
+}
+
+func (b *chunkBuffer) WriteAt(p []byte, off int64) (int, error) {
+	return copy(b.dta[int(off)-b.off:], p), nil
+}
+
+// rangeResult is the outcome of fetching a single chunk, buf is returned to the pool once the chunk is read.
+type rangeResult struct {
+	buf *[]byte
+	dta []byte
+	err error
+}
+
+// rangeReader fetches the entity in ranges concurrently and returns them in order as a single stream.
+// The read-ahead window is bounded, so no more than ReadConcurrency chunks are kept in memory besides the one being read.
+// All of the ranges are pinned to the ETag, read fails with ErrContentChanged if the entity is replaced in the middle.
+type rangeReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wgp    *sync.WaitGroup
+	ord    chan chan *rangeResult
+	pol    *sync.Pool
+	buf    *[]byte
+	cur    []byte
+	pos    int
+	err    error
+}
+
+func (c *Client) newRangeReader(ctx context.Context, pth string, hds *schema.Headers) *rangeReader {
+	csz := c.ReadChunkSize
+
+	if csz <= 0 {
+		csz = c.DownloadChunkSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	rdr := &rangeReader{
+		ctx:    ctx,
+		cancel: cancel,
+		wgp:    new(sync.WaitGroup),
+		ord:    make(chan chan *rangeResult, c.ReadConcurrency),
+		pol: &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, csz)
+				return &buf
+			},
+		},
+	}
+
+	rdr.wgp.Add(1)
+
+	go func() {
+		defer rdr.wgp.Done()
+		defer close(rdr.ord)
+
+		for _, cnk := range splitChunks(hds.ContentLength, csz) {
+			rsc := make(chan *rangeResult, 1)
+
+			// blocks once the window is full, until the reader consumes the oldest chunk
+			select {
+			case rdr.ord <- rsc:
+			case <-ctx.Done():
+				return
+			}
+
+			rdr.wgp.Add(1)
+
+			go func(cnk *chunk) {
+				defer rdr.wgp.Done()
+
+				buf := rdr.pol.Get().(*[]byte)
+				cbf := &chunkBuffer{
+					off: cnk.start,
+					dta: (*buf)[:cnk.end-cnk.start],
+				}
+				err := c.downloadChunk(ctx, pth, hds, cbf, cnk, nil, nil)
+				rsc <- &rangeResult{buf: buf, dta: cbf.dta, err: err}
+			}(cnk)
+		}
+	}()
+
+	return rdr
+}
+
+// Read returns the data of the fetched chunks in order.
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for r.err == nil && r.pos >= len(r.cur) {
+		r.next()
+	}
+
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n := copy(p, r.cur[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+// next waits for the next chunk in order and releases the current one.
+func (r *rangeReader) next() {
+	if r.buf != nil {
+		r.pol.Put(r.buf)
+		r.buf = nil
+		r.cur = nil
+		r.pos = 0
+	}
+
+	rsc, ok := <-r.ord
+
+	if !ok {
+		r.err = io.EOF
+
+		if err := r.ctx.Err(); err != nil {
+			r.err = err
+		}
+
+		return
+	}
+
+	select {
+	case res := <-rsc:
+		r.buf, r.cur, r.err = res.buf, res.dta, res.err
+	case <-r.ctx.Done():
+		r.err = r.ctx.Err()
+	}
+}
+
+// Close stops fetching the chunks and waits for all of the workers to exit.
+func (r *rangeReader) Close() error {
+	r.cancel()
+	r.wgp.Wait()
+	return nil
+}
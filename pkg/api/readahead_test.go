@@ -0,0 +1,158 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type readAheadTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	clt api.API
+	arc []byte
+	nms []string
+	nrg bool
+	fst string
+	etg string
+	chg int32
+	irg bool
+	rgs int32
+	err bool
+	eis error
+}
+
+func (s *readAheadTestSuite) SetupTest() {
+	atomic.StoreInt32(&s.rgs, 0)
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.nrg {
+			_, _ = w.Write(s.arc)
+			return
+		}
+
+		if len(s.etg) > 0 {
+			w.Header().Set("ETag", fmt.Sprintf(`"%s"`, s.etg))
+		}
+
+		if rng := r.Header.Get("Range"); len(rng) > 0 {
+			rgs := atomic.AddInt32(&s.rgs, 1)
+
+			if len(s.fst) > 0 && strings.HasPrefix(rng, s.fst) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			// archive was replaced after the first ranges were read
+			if s.chg > 0 && rgs > s.chg {
+				w.Header().Set("ETag", `"changed"`)
+			}
+
+			if s.irg {
+				_, _ = w.Write(s.arc)
+				return
+			}
+		}
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.arc))
+	}))
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.BaseUrl = fmt.Sprintf("%s/", s.srv.URL)
+		clt.ReadConcurrency = 3
+		clt.ReadChunkSize = 64
+	})
+}
+
+func (s *readAheadTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *readAheadTestSuite) TestReadSnapshot() {
+	nms := []string{}
+	err := s.clt.ReadSnapshot(s.ctx, "enwiki_namespace_0", func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	if s.err {
+		s.Assert().Error(err)
+
+		if s.eis != nil {
+			s.Assert().ErrorIs(err, s.eis)
+		}
+
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.nms, nms)
+
+	if s.nrg {
+		s.Assert().Zero(atomic.LoadInt32(&s.rgs))
+	} else {
+		s.Assert().Equal(int32((len(s.arc)+63)/64), atomic.LoadInt32(&s.rgs))
+	}
+}
+
+func TestReadAhead(t *testing.T) {
+	nms := []string{}
+	lns := []string{}
+
+	for i := 0; i < 50; i++ {
+		nms = append(nms, fmt.Sprintf("Article_%d", i))
+		lns = append(lns, fmt.Sprintf(`{"name":"Article_%d","abstract":"%s"}`, i, strings.Repeat(fmt.Sprint(i), 20)))
+	}
+
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": lns[:25],
+		"enwiki_namespace_0_1.ndjson": lns[25:],
+	})
+
+	for _, testCase := range []*readAheadTestSuite{
+		{
+			arc: arc,
+			nms: nms,
+		},
+		{
+			arc: arc,
+			nms: nms,
+			nrg: true,
+		},
+		{
+			arc: arc,
+			fst: "bytes=128-",
+			err: true,
+		},
+		{
+			arc: arc,
+			nms: nms,
+			etg: "etag",
+		},
+		{
+			arc: arc,
+			etg: "etag",
+			chg: 2,
+			err: true,
+			eis: api.ErrContentChanged,
+		},
+		{
+			arc: arc,
+			etg: "etag",
+			irg: true,
+			err: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,159 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type readAllTestSuite struct {
+	suite.Suite
+	clt api.API
+	arc []byte
+	dcs int
+	cnc int
+}
+
+func (s *readAllTestSuite) SetupTest() {
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.DecodeConcurrency = s.dcs
+		clt.DecodeOrdered = true
+	})
+}
+
+func (s *readAllTestSuite) TestReadAll() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if s.cnc == 0 {
+		cancel()
+	}
+
+	cnt := 0
+	err := s.clt.ReadAll(ctx, bytes.NewReader(s.arc), func(art *schema.Article) error {
+		cnt++
+
+		if cnt == s.cnc {
+			cancel()
+		}
+
+		return nil
+	})
+
+	s.Assert().ErrorIs(err, context.Canceled)
+
+	// concurrent decoding can deliver the articles that were already decoded
+	if s.dcs > 1 {
+		s.Assert().Less(cnt, 100)
+	} else {
+		s.Assert().Equal(s.cnc, cnt)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	lns := []string{}
+
+	for i := 0; i < 100; i++ {
+		lns = append(lns, fmt.Sprintf(`{"name":"Article_%d"}`, i))
+	}
+
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": lns[:50],
+		"enwiki_namespace_0_1.ndjson": lns[50:],
+	})
+
+	for _, testCase := range []*readAllTestSuite{
+		{
+			arc: arc,
+		},
+		{
+			arc: arc,
+			cnc: 1,
+		},
+		{
+			arc: arc,
+			cnc: 50,
+		},
+		{
+			arc: arc,
+			dcs: 4,
+			cnc: 1,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type readFiltersTestSuite struct {
+	suite.Suite
+	clt api.API
+	arc []byte
+	fds []string
+	fts []*api.Filter
+	nms []string
+	sts *api.ReadStats
+}
+
+func (s *readFiltersTestSuite) SetupTest() {
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.ReadFields = s.fds
+		clt.ReadFilters = s.fts
+		clt.Stats = func(sts *api.ReadStats) {
+			s.sts = sts
+		}
+	})
+}
+
+func (s *readFiltersTestSuite) TestReadAll() {
+	nms := []string{}
+	err := s.clt.ReadAll(context.Background(), bytes.NewReader(s.arc), func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.nms, nms)
+	s.Assert().Equal(int64(len(s.nms)), s.sts.Articles)
+	s.Assert().Equal(int64(4-len(s.nms)), s.sts.Filtered)
+}
+
+func TestReadFilters(t *testing.T) {
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": {
+			`{"name":"Earth","is_part_of":{"identifier":"enwiki"},"event":{"type":"update"}}`,
+			`{"name":"Mars","is_part_of":{"identifier":"enwiki"},"event":{"type":"delete"}}`,
+			`{"name":"Terre","is_part_of":{"identifier":"frwiki"},"event":{"type":"update"}}`,
+			`{"name":"Venus","is_part_of":{"identifier":"enwiki"},"event":{"type":"update"}}`,
+		},
+	})
+
+	for _, testCase := range []*readFiltersTestSuite{
+		{
+			arc: arc,
+			nms: []string{"Earth", "Mars", "Terre", "Venus"},
+		},
+		{
+			arc: arc,
+			fts: []*api.Filter{
+				{Field: "is_part_of.identifier", Value: "enwiki"},
+				{Field: "event.type", Value: "update"},
+			},
+			nms: []string{"Earth", "Venus"},
+		},
+		{
+			arc: arc,
+			fds: []string{"name"},
+			fts: []*api.Filter{
+				{Field: "is_part_of.identifier", Value: "frwiki"},
+			},
+			nms: []string{"Terre"},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
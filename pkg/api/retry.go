@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/apierror"
+)
+
+// NewRetryPolicy returns a retry policy with reasonable defaults.
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 5,
+		MinBackoff:  time.Millisecond * 500,
+		MaxBackoff:  time.Second * 30,
+		Jitter:      0.5,
+	}
+}
+
+// WithRetryPolicy is a functional option for NewClient that enables retries of the failed requests.
+func WithRetryPolicy(rtp *RetryPolicy) func(clt *Client) {
+	return func(clt *Client) {
+		clt.RetryPolicy = rtp
+	}
+}
+
+// RetryPolicy describes how the client should retry the failed requests.
+// Only idempotent requests are retried, streaming reads are resumed from the last read byte
+// (up to MaxAttempts times in a row without receiving any data).
+type RetryPolicy struct {
+	MaxAttempts int           // Maximum number of attempts, including the first one.
+	MinBackoff  time.Duration // Backoff before the first retry, doubles with every attempt.
+	MaxBackoff  time.Duration // Upper limit for the backoff (Retry-After header is not limited by it).
+	Jitter      float64       // Portion of the backoff that is randomized, from 0 to 1.
+}
+
+// Backoff returns amount of time to wait before the next attempt.
+// Honours Retry-After header if server responded with one.
+func (r *RetryPolicy) Backoff(att int, err error) time.Duration {
+	if aer, ok := apierror.As(err); ok && aer.RetryAfter > 0 {
+		return aer.RetryAfter
+	}
+
+	bof := r.MinBackoff
+
+	for i := 1; i < att && bof < r.MaxBackoff; i++ {
+		bof *= 2
+	}
+
+	if r.MaxBackoff > 0 && bof > r.MaxBackoff {
+		bof = r.MaxBackoff
+	}
+
+	if r.Jitter > 0 && bof > 0 {
+		jtr := time.Duration(float64(bof) * r.Jitter)
+		bof = bof - jtr + time.Duration(rand.Int63n(int64(jtr)+1))
+	}
+
+	return bof
+}
+
+// IsRetryable checks if the request that failed with the error can be retried.
+func (r *RetryPolicy) IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if aer, ok := apierror.As(err); ok {
+		return aer.IsTemporary()
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	ner := net.Error(nil)
+	return errors.As(err, &ner)
+}
+
+func (c *Client) retry(ctx context.Context, fnc func() error) error {
+	if c.RetryPolicy == nil {
+		return fnc()
+	}
+
+	for att := 1; ; att++ {
+		err := fnc()
+
+		if err == nil || att >= c.RetryPolicy.MaxAttempts || !c.RetryPolicy.IsRetryable(err) {
+			return err
+		}
+
+		tmr := time.NewTimer(c.RetryPolicy.Backoff(att, err))
+
+		select {
+		case <-ctx.Done():
+			tmr.Stop()
+			return ctx.Err()
+		case <-tmr.C:
+		}
+	}
+}
+
+// resumableReader reads the response body and in case of a transient failure
+// re-requests the rest of the content with a range request.
+// Resumes are limited by the retry policy, the counter is reset once the data starts flowing again.
+type resumableReader struct {
+	ctx context.Context
+	clt *Client
+	pth string
+	irg string
+	off int64
+	att int
+	bdy io.ReadCloser
+}
+
+func (r *resumableReader) Read(p []byte) (int, error) {
+	n, err := r.bdy.Read(p)
+	r.off += int64(n)
+
+	if n > 0 {
+		r.att = 0
+	}
+
+	if err == nil || err == io.EOF || r.clt.RetryPolicy == nil || !r.clt.RetryPolicy.IsRetryable(err) {
+		return n, err
+	}
+
+	// the first attempt is the connection that just failed
+	if r.att++; r.att >= r.clt.RetryPolicy.MaxAttempts {
+		return n, err
+	}
+
+	tmr := time.NewTimer(r.clt.RetryPolicy.Backoff(r.att, err))
+
+	select {
+	case <-r.ctx.Done():
+		tmr.Stop()
+		return n, r.ctx.Err()
+	case <-tmr.C:
+	}
+
+	if err := r.resume(); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+func (r *resumableReader) resume() error {
+	_ = r.bdy.Close()
+
+	return r.clt.retry(r.ctx, func() error {
+		hrq, err := r.clt.newRequest(r.ctx, r.clt.BaseUrl, http.MethodGet, r.pth, nil)
+
+		if err != nil {
+			return err
+		}
+
+		hrq.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.off))
+
+		if len(r.irg) > 0 {
+			hrq.Header.Set("If-Range", r.irg)
+		}
+
+		res, err := r.clt.do(hrq)
+
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode != http.StatusPartialContent {
+			_ = res.Body.Close()
+			return fmt.Errorf("%w: can't resume reading '%s'", ErrContentChanged, r.pth)
+		}
+
+		r.bdy = res.Body
+		return nil
+	})
+}
+
+func (r *resumableReader) Close() error {
+	return r.bdy.Close()
+}
@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type retryTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	clt api.API
+	rtp *api.RetryPolicy
+	fls int
+	fst int
+	arc []byte
+	rfl bool
+	cls int32
+	ecl int
+	err bool
+}
+
+func (s *retryTestSuite) createServer() http.Handler {
+	rtr := http.NewServeMux()
+
+	rtr.HandleFunc("/v2/codes", func(w http.ResponseWriter, r *http.Request) {
+		cls := atomic.AddInt32(&s.cls, 1)
+
+		if int(cls) <= s.fls {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(s.fst)
+			return
+		}
+
+		_, _ = w.Write([]byte(`[{"identifier":"wiki"}]`))
+	})
+
+	rtr.HandleFunc("/v2/snapshots/enwiki_namespace_0/download", func(w http.ResponseWriter, r *http.Request) {
+		cls := atomic.AddInt32(&s.cls, 1)
+		w.Header().Set("ETag", `"etag"`)
+
+		// resumed reads fail before sending any data
+		if rng := r.Header.Get("Range"); s.rfl && len(rng) > 0 {
+			off, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", off, len(s.arc)-1, len(s.arc)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(s.arc)-off))
+			w.WriteHeader(http.StatusPartialContent)
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		if int(cls) <= s.fls {
+			w.Header().Set("Content-Length", strconv.Itoa(len(s.arc)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(s.arc[:len(s.arc)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.arc))
+	})
+
+	return rtr
+}
+
+func (s *retryTestSuite) SetupTest() {
+	atomic.StoreInt32(&s.cls, 0)
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(s.createServer())
+	s.clt = api.NewClient(
+		func(clt *api.Client) {
+			clt.BaseUrl = fmt.Sprintf("%s/", s.srv.URL)
+		},
+		api.WithRetryPolicy(s.rtp),
+	)
+}
+
+func (s *retryTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *retryTestSuite) TestGetCodes() {
+	if s.fst == 0 {
+		s.T().Skip("only for status failures")
+	}
+
+	cds, err := s.clt.GetCodes(s.ctx, nil)
+
+	if s.err {
+		s.Assert().Error(err)
+		s.Assert().Equal(s.fst == http.StatusServiceUnavailable, apierror.IsTemporary(err))
+	} else {
+		s.Assert().NoError(err)
+		s.Assert().NotEmpty(cds)
+	}
+
+	s.Assert().Equal(s.ecl, int(atomic.LoadInt32(&s.cls)))
+}
+
+func (s *retryTestSuite) TestReadSnapshot() {
+	if s.arc == nil {
+		s.T().Skip("only for interrupted reads")
+	}
+
+	nms := []string{}
+	err := s.clt.ReadSnapshot(s.ctx, "enwiki_namespace_0", func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	if s.err {
+		s.Assert().Error(err)
+	} else {
+		s.Assert().NoError(err)
+		s.Assert().Equal([]string{"Earth", "Mars"}, nms)
+	}
+
+	s.Assert().Equal(s.ecl, int(atomic.LoadInt32(&s.cls)))
+}
+
+func TestRetry(t *testing.T) {
+	rtp := &api.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond * 10,
+		Jitter:      0.5,
+	}
+
+	arc := createArchive(map[string][]string{
+		"enwiki_namespace_0_0.ndjson": {
+			`{"name":"Earth","article_body":{"wikitext":"` + string(bytes.Repeat([]byte("a"), 4096)) + `"}}`,
+			`{"name":"Mars"}`,
+		},
+	})
+
+	for _, testCase := range []*retryTestSuite{
+		{
+			rtp: rtp,
+			fls: 2,
+			fst: http.StatusServiceUnavailable,
+			ecl: 3,
+		},
+		{
+			rtp: rtp,
+			fls: 3,
+			fst: http.StatusServiceUnavailable,
+			ecl: 3,
+			err: true,
+		},
+		{
+			rtp: rtp,
+			fls: 2,
+			fst: http.StatusNotFound,
+			ecl: 1,
+			err: true,
+		},
+		{
+			fls: 1,
+			fst: http.StatusServiceUnavailable,
+			ecl: 1,
+			err: true,
+		},
+		{
+			rtp: rtp,
+			fls: 1,
+			arc: arc,
+			ecl: 2,
+		},
+		{
+			fls: 1,
+			arc: arc,
+			ecl: 1,
+			err: true,
+		},
+		{
+			rtp: rtp,
+			fls: 1,
+			arc: arc,
+			rfl: true,
+			ecl: 3,
+			err: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// ErrStreamClosed is reported when the server closed the stream without an error.
+var ErrStreamClosed = errors.New("stream was closed by the server")
+
+// errNoTokenRefresher is returned when the stream can't refresh the rejected access token.
+var errNoTokenRefresher = errors.New("stream has no token source to refresh the token")
+
+// streamFields are needed to track the position of the stream, added to the request if the fields are limited.
+var streamFields = []string{"event.partition", "event.offset", "event.date_published"}
+
+// NewStream creates a managed articles stream that reconnects with backoff
+// and resumes from the last handled article, by default it reconnects until the context is cancelled.
+func NewStream(ast ArticlesStreamer, req *Request, ops ...func(stm *Stream)) *Stream {
+	rtp := NewRetryPolicy()
+	rtp.MaxAttempts = 0
+
+	stm := &Stream{
+		RetryPolicy: rtp,
+		streamer:    ast,
+		req:         req,
+		ofs:         map[int]int64{},
+		spp:         map[int]time.Time{},
+	}
+
+	if stm.req == nil {
+		stm.req = new(Request)
+	}
+
+	for _, opt := range ops {
+		opt(stm)
+	}
+
+	return stm
+}
+
+// Stream keeps the articles stream open, satisfies "at least once" delivery for the articles.
+// The position (partition offsets and publishing dates) is recorded only after the callback succeeds,
+// on reconnect the stream resumes from the last handled offset of each partition, so the last article can be delivered again.
+// If DecodeConcurrency is used, DecodeOrdered has to be set to keep the guarantee.
+type Stream struct {
+	RetryPolicy *RetryPolicy    // Backoff between reconnects, MaxAttempts limits consecutive connections without articles (0 is unlimited).
+	OnError     func(err error) // Called with every connection error before reconnecting, optional.
+	streamer    ArticlesStreamer
+	req         *Request
+	mut         sync.Mutex
+	snc         *time.Time
+	ofs         map[int]int64
+	spp         map[int]time.Time
+}
+
+// Subscribe opens the stream in a blocking call, returns when the context is cancelled,
+// the callback fails or the connection fails with a permanent error.
+// Unauthorized (401) response is permanent, unless the streamer is a Client with the TokenSource
+// that implements auth.TokenRefresher, in that case the token is refreshed and the stream reconnects once.
+func (s *Stream) Subscribe(ctx context.Context, cbk ReadCallback) error {
+	rfd := false
+
+	for att := 1; ; att++ {
+		cnt := 0
+		var cer error
+
+		err := s.streamer.StreamArticles(ctx, s.Resume(), func(art *schema.Article) error {
+			if err := cbk(art); err != nil {
+				s.mut.Lock()
+				cer = err
+				s.mut.Unlock()
+				return err
+			}
+
+			s.track(art)
+
+			s.mut.Lock()
+			cnt++
+			s.mut.Unlock()
+			return nil
+		})
+
+		if cer != nil {
+			return cer
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// connection that delivered articles is considered healthy
+		if cnt > 0 {
+			att = 1
+			rfd = false
+		}
+
+		if err == nil {
+			err = ErrStreamClosed
+		} else if apierror.IsUnauthorized(err) && !rfd {
+			// the token was rejected (for example revoked), reconnect once with a fresh one
+			if rer := s.refreshToken(ctx); rer != nil {
+				return err
+			}
+
+			rfd = true
+		} else if !s.RetryPolicy.IsRetryable(err) {
+			return err
+		}
+
+		if s.OnError != nil {
+			s.OnError(err)
+		}
+
+		if s.RetryPolicy.MaxAttempts > 0 && att >= s.RetryPolicy.MaxAttempts {
+			return err
+		}
+
+		tmr := time.NewTimer(s.RetryPolicy.Backoff(att, err))
+
+		select {
+		case <-ctx.Done():
+			tmr.Stop()
+			return ctx.Err()
+		case <-tmr.C:
+		}
+	}
+}
+
+// Resume returns the request that continues the stream from the last handled articles,
+// parts, fields and filters of the initial request are kept.
+func (s *Stream) Resume() *Request {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	req := *s.req
+
+	if len(req.Fields) > 0 {
+		req.Fields = append([]string{}, req.Fields...)
+
+		for _, fld := range streamFields {
+			if !containsField(req.Fields, fld) {
+				req.Fields = append(req.Fields, fld)
+			}
+		}
+	}
+
+	if s.snc != nil {
+		snc := *s.snc
+		req.Since = &snc
+	}
+
+	if len(s.ofs) > 0 {
+		req.Offsets = map[int]int64{}
+
+		for ptn, off := range s.req.Offsets {
+			req.Offsets[ptn] = off
+		}
+
+		for ptn, off := range s.ofs {
+			req.Offsets[ptn] = off
+		}
+	}
+
+	if len(s.spp) > 0 {
+		req.SincePerPartition = map[int]time.Time{}
+
+		for ptn, snc := range s.req.SincePerPartition {
+			req.SincePerPartition[ptn] = snc
+		}
+
+		for ptn, snc := range s.spp {
+			req.SincePerPartition[ptn] = snc
+		}
+
+		// offset is the exact position, the partition that has one is not resumed by the time
+		for ptn := range req.Offsets {
+			delete(req.SincePerPartition, ptn)
+		}
+
+		if len(req.SincePerPartition) == 0 {
+			req.SincePerPartition = nil
+		}
+	}
+
+	return &req
+}
+
+// Offsets returns the offsets of the last handled articles per partition.
+func (s *Stream) Offsets() map[int]int64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ofs := map[int]int64{}
+
+	for ptn, off := range s.ofs {
+		ofs[ptn] = off
+	}
+
+	return ofs
+}
+
+// track records the position of the handled article.
+func (s *Stream) track(art *schema.Article) {
+	if art == nil || art.Event == nil {
+		return
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	evt := art.Event
+
+	// without partitions the only way to resume is the global timestamp,
+	// otherwise partitions that didn't deliver anything yet have to start from the initial position
+	if evt.Partition == nil {
+		if evt.DatePublished != nil && (s.snc == nil || evt.DatePublished.After(*s.snc)) {
+			dpb := *evt.DatePublished
+			s.snc = &dpb
+		}
+
+		return
+	}
+
+	if off, ok := s.ofs[*evt.Partition]; evt.Offset != nil && (!ok || *evt.Offset > off) {
+		s.ofs[*evt.Partition] = *evt.Offset
+	}
+
+	if snc, ok := s.spp[*evt.Partition]; evt.DatePublished != nil && (!ok || evt.DatePublished.After(snc)) {
+		s.spp[*evt.Partition] = *evt.DatePublished
+	}
+}
+
+// refreshToken forces the refresh of the client token, fails if the streamer can't refresh it.
+func (s *Stream) refreshToken(ctx context.Context) error {
+	clt, ok := s.streamer.(*Client)
+
+	if !ok {
+		return errNoTokenRefresher
+	}
+
+	trf, ok := clt.TokenSource.(auth.TokenRefresher)
+
+	if !ok {
+		return errNoTokenRefresher
+	}
+
+	_, err := trf.Refresh(ctx)
+	return err
+}
+
+// containsField checks if the field is selected by the list, including the wildcards like `event.*`.
+func containsField(fds []string, fld string) bool {
+	for _, sel := range fds {
+		if sel == fld || strings.HasPrefix(fld, sel+".") {
+			return true
+		}
+
+		if strings.HasSuffix(sel, "*") && strings.HasPrefix(fld, strings.TrimSuffix(sel, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,523 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/protsack-stephan/wme/pkg/idle"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type streamTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	clt api.API
+	req *api.Request
+	lns []string
+	mtd string
+	bdy map[string]interface{}
+	nms []string
+}
+
+func (s *streamTestSuite) SetupTest() {
+	s.bdy = nil
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mtd = r.Method
+		_ = json.NewDecoder(r.Body).Decode(&s.bdy)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(strings.Join(s.lns, "\n")))
+	}))
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.RealtimeURL = fmt.Sprintf("%s/", s.srv.URL)
+	})
+}
+
+func (s *streamTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *streamTestSuite) TestStreamArticles() {
+	nms := []string{}
+	err := s.clt.StreamArticles(context.Background(), s.req, func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.nms, nms)
+	s.Assert().Equal(http.MethodGet, s.mtd)
+
+	dta, _ := json.Marshal(s.req)
+	bdy := map[string]interface{}{}
+	_ = json.Unmarshal(dta, &bdy)
+
+	s.Assert().Equal(bdy, s.bdy)
+}
+
+func TestStream(t *testing.T) {
+	snc := time.Date(2023, 2, 28, 10, 0, 0, 0, time.UTC)
+	lns := []string{`{"name":"Earth"}`, `{"name":"Mars"}`}
+
+	for _, testCase := range []*streamTestSuite{
+		{
+			req: &api.Request{},
+			lns: lns,
+			nms: []string{"Earth", "Mars"},
+		},
+		{
+			req: &api.Request{
+				Fields: []string{"name", "event.*"},
+				Parts:  []int{0, 1},
+				Offsets: map[int]int64{
+					0: 100,
+					7: 42,
+				},
+			},
+			lns: lns,
+			nms: []string{"Earth", "Mars"},
+		},
+		{
+			req: &api.Request{
+				Since: &snc,
+				Parts: []int{9},
+				SincePerPartition: map[int]time.Time{
+					46: snc.Add(time.Hour),
+				},
+				Filters: []*api.Filter{
+					{Field: "is_part_of.identifier", Value: "enwiki"},
+				},
+			},
+			lns: lns,
+			nms: []string{"Earth", "Mars"},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type streamConnection struct {
+	ats []*schema.Article
+	err error
+}
+
+type fakeStreamer struct {
+	mut sync.Mutex
+	cns []*streamConnection
+	rqs []*api.Request
+	cnl context.CancelFunc
+}
+
+func (f *fakeStreamer) StreamArticles(ctx context.Context, req *api.Request, cbk api.ReadCallback) error {
+	f.mut.Lock()
+	f.rqs = append(f.rqs, req)
+	cnn := len(f.rqs) - 1
+	f.mut.Unlock()
+
+	// stop the stream once all of the connections were used
+	if cnn >= len(f.cns) {
+		f.cnl()
+		return ctx.Err()
+	}
+
+	for _, art := range f.cns[cnn].ats {
+		if err := cbk(art); err != nil {
+			return err
+		}
+	}
+
+	return f.cns[cnn].err
+}
+
+func newStreamArticle(nme string, ptn int, off int64, dpb time.Time) *schema.Article {
+	return &schema.Article{
+		Name: nme,
+		Event: &schema.Event{
+			Partition:     &ptn,
+			Offset:        &off,
+			DatePublished: &dpb,
+		},
+	}
+}
+
+type managedStreamTestSuite struct {
+	suite.Suite
+	req *api.Request
+	cns []*streamConnection
+	mxa int
+	cbe error
+	nms []string
+	rqs []*api.Request
+	err error
+	ers int
+}
+
+func (s *managedStreamTestSuite) TestSubscribe() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	fsr := &fakeStreamer{cns: s.cns, cnl: cancel}
+	ers := 0
+	stm := api.NewStream(fsr, s.req, func(stm *api.Stream) {
+		stm.RetryPolicy.MinBackoff = time.Millisecond
+		stm.RetryPolicy.MaxAttempts = s.mxa
+		stm.OnError = func(err error) {
+			ers++
+		}
+	})
+
+	nms := []string{}
+	err := stm.Subscribe(ctx, func(art *schema.Article) error {
+		if s.cbe != nil && len(nms) == 1 {
+			return s.cbe
+		}
+
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	s.Assert().EqualError(err, s.err.Error())
+	s.Assert().Equal(s.nms, nms)
+	s.Assert().Equal(s.ers, ers)
+	s.Assert().GreaterOrEqual(len(fsr.rqs), len(s.rqs))
+	s.Assert().Equal(s.rqs, fsr.rqs[:len(s.rqs)])
+}
+
+func TestManagedStream(t *testing.T) {
+	snc := time.Date(2023, 2, 28, 10, 0, 0, 0, time.UTC)
+	dpb := snc.Add(time.Hour)
+	ptn := 5
+
+	for _, testCase := range []*managedStreamTestSuite{
+		{
+			req: &api.Request{
+				Fields: []string{"name"},
+				Parts:  []int{0},
+				Since:  &snc,
+			},
+			cns: []*streamConnection{
+				{
+					ats: []*schema.Article{
+						newStreamArticle("Earth", 0, 10, dpb),
+						newStreamArticle("Mars", 1, 20, dpb),
+						newStreamArticle("Venus", 0, 11, dpb.Add(time.Minute)),
+					},
+					err: io.ErrUnexpectedEOF,
+				},
+				{
+					err: io.ErrUnexpectedEOF,
+				},
+				{
+					ats: []*schema.Article{
+						newStreamArticle("Venus", 0, 11, dpb.Add(time.Minute)),
+						newStreamArticle("Pluto", 2, 5, dpb),
+					},
+				},
+			},
+			nms: []string{"Earth", "Mars", "Venus", "Venus", "Pluto"},
+			rqs: []*api.Request{
+				{
+					Fields: []string{"name", "event.partition", "event.offset", "event.date_published"},
+					Parts:  []int{0},
+					Since:  &snc,
+				},
+				{
+					Fields:  []string{"name", "event.partition", "event.offset", "event.date_published"},
+					Parts:   []int{0},
+					Since:   &snc,
+					Offsets: map[int]int64{0: 11, 1: 20},
+				},
+			},
+			err: context.Canceled,
+			ers: 3,
+		},
+		{
+			req: &api.Request{
+				Fields:  []string{"name", "event.*"},
+				Offsets: map[int]int64{3: 100},
+			},
+			cns: []*streamConnection{
+				{
+					ats: []*schema.Article{
+						newStreamArticle("Earth", 0, 10, dpb),
+						{Name: "Mars", Event: &schema.Event{Partition: &ptn, DatePublished: &dpb}},
+					},
+				},
+			},
+			nms: []string{"Earth", "Mars"},
+			rqs: []*api.Request{
+				{
+					Fields:  []string{"name", "event.*"},
+					Offsets: map[int]int64{3: 100},
+				},
+				{
+					Fields:            []string{"name", "event.*"},
+					Offsets:           map[int]int64{0: 10, 3: 100},
+					SincePerPartition: map[int]time.Time{5: dpb},
+				},
+			},
+			err: context.Canceled,
+			ers: 1,
+		},
+		{
+			cns: []*streamConnection{
+				{
+					ats: []*schema.Article{
+						{Name: "Earth", Event: &schema.Event{DatePublished: &dpb}},
+					},
+					err: io.ErrUnexpectedEOF,
+				},
+			},
+			nms: []string{"Earth"},
+			rqs: []*api.Request{
+				{},
+				{Since: &dpb},
+			},
+			err: context.Canceled,
+			ers: 1,
+		},
+		{
+			cns: []*streamConnection{
+				{
+					ats: []*schema.Article{
+						newStreamArticle("Earth", 0, 10, dpb),
+						newStreamArticle("Mars", 0, 11, dpb),
+					},
+				},
+			},
+			cbe: errors.New("stop streaming"),
+			nms: []string{"Earth"},
+			rqs: []*api.Request{{}},
+			err: errors.New("stop streaming"),
+		},
+		{
+			cns: []*streamConnection{
+				{
+					err: errors.New("unauthorized"),
+				},
+			},
+			nms: []string{},
+			rqs: []*api.Request{{}},
+			err: errors.New("unauthorized"),
+		},
+		{
+			cns: []*streamConnection{
+				{err: io.ErrUnexpectedEOF},
+				{err: io.ErrUnexpectedEOF},
+				{err: io.ErrUnexpectedEOF},
+			},
+			mxa: 3,
+			nms: []string{},
+			rqs: []*api.Request{{}, {}, {}},
+			err: io.ErrUnexpectedEOF,
+			ers: 3,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type fakeTokenSource struct {
+	tkn string
+	rfs string
+}
+
+func (f *fakeTokenSource) GetToken(_ context.Context) (string, error) {
+	return f.tkn, nil
+}
+
+type fakeTokenRefresher struct {
+	fakeTokenSource
+}
+
+func (f *fakeTokenRefresher) Refresh(_ context.Context) (string, error) {
+	f.tkn = f.rfs
+	return f.tkn, nil
+}
+
+func (f *fakeTokenRefresher) RefreshIfCurrent(ctx context.Context, old string) (string, error) {
+	if old != f.tkn {
+		return f.tkn, nil
+	}
+
+	return f.Refresh(ctx)
+}
+
+type unauthorizedStreamTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	clt api.API
+	tks auth.TokenGetter
+	cls int32
+	nms []string
+	ecl int
+	err bool
+}
+
+func (s *unauthorizedStreamTestSuite) SetupTest() {
+	atomic.StoreInt32(&s.cls, 0)
+
+	// rejects the revoked token, and closes the stream after a single article
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.cls, 1)
+
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":401,"message":"token was revoked"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"name":"Earth"}` + "\n"))
+	}))
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.RealtimeURL = fmt.Sprintf("%s/", s.srv.URL)
+		clt.TokenSource = s.tks
+	})
+}
+
+func (s *unauthorizedStreamTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *unauthorizedStreamTestSuite) TestSubscribe() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	stm := api.NewStream(s.clt, new(api.Request), func(stm *api.Stream) {
+		stm.RetryPolicy.MinBackoff = time.Millisecond
+	})
+
+	nms := []string{}
+	err := stm.Subscribe(ctx, func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		cancel()
+		return nil
+	})
+
+	if s.err {
+		aer, ok := apierror.As(err)
+		s.Assert().True(ok)
+		s.Assert().Equal(http.StatusUnauthorized, aer.StatusCode)
+	} else {
+		s.Assert().ErrorIs(err, context.Canceled)
+	}
+
+	s.Assert().Equal(s.nms, nms)
+	s.Assert().Equal(s.ecl, int(atomic.LoadInt32(&s.cls)))
+}
+
+func TestUnauthorizedStream(t *testing.T) {
+	for _, testCase := range []*unauthorizedStreamTestSuite{
+		{
+			tks: &fakeTokenRefresher{fakeTokenSource{tkn: "revoked", rfs: "fresh"}},
+			nms: []string{"Earth"},
+			ecl: 2,
+		},
+		{
+			tks: &fakeTokenRefresher{fakeTokenSource{tkn: "revoked", rfs: "revoked"}},
+			nms: []string{},
+			ecl: 2,
+			err: true,
+		},
+		{
+			tks: &fakeTokenSource{tkn: "revoked"},
+			nms: []string{},
+			ecl: 1,
+			err: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type idleStreamTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	clt api.API
+	tmt time.Duration
+}
+
+func (s *idleStreamTestSuite) SetupTest() {
+	// sends a single article and stalls without closing the connection
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"name":"Earth"}` + "\n"))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	s.clt = api.NewClient(func(clt *api.Client) {
+		clt.RealtimeURL = fmt.Sprintf("%s/", s.srv.URL)
+		clt.IdleTimeout = s.tmt
+	})
+}
+
+func (s *idleStreamTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *idleStreamTestSuite) TestStreamArticles() {
+	nms := []string{}
+	err := s.clt.StreamArticles(context.Background(), new(api.Request), func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	s.Assert().True(idle.IsTimeout(err))
+	s.Assert().Equal([]string{"Earth"}, nms)
+}
+
+func (s *idleStreamTestSuite) TestSubscribe() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	ers := []error{}
+	stm := api.NewStream(s.clt, new(api.Request), func(stm *api.Stream) {
+		stm.RetryPolicy.MinBackoff = time.Millisecond
+		stm.OnError = func(err error) {
+			if ers = append(ers, err); len(ers) == 2 {
+				cancel()
+			}
+		}
+	})
+
+	nms := []string{}
+	err := stm.Subscribe(ctx, func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	s.Assert().ErrorIs(err, context.Canceled)
+	s.Assert().Len(ers, 2)
+
+	for _, err := range ers {
+		s.Assert().True(idle.IsTimeout(err))
+	}
+
+	s.Assert().GreaterOrEqual(len(nms), 2)
+}
+
+func TestIdleStream(t *testing.T) {
+	for _, testCase := range []*idleStreamTestSuite{
+		{
+			tmt: time.Millisecond * 50,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// ErrNoChecksum is returned when the headers don't contain a checksum that can be verified,
+// the content is neither verified nor known to be corrupted in this case.
+var ErrNoChecksum = errors.New("no checksum to verify against")
+
+var (
+	etagMD5          = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+	etagMultipartMD5 = regexp.MustCompile(`^([0-9a-fA-F]{32})-([0-9]+)$`)
+)
+
+// commonPartSizes are part sizes commonly used by multipart uploads,
+// used to guess the part size for S3-style ETag verification.
+var commonPartSizes = []int64{
+	5 * 1024 * 1024,
+	8 * 1024 * 1024,
+	10 * 1024 * 1024,
+	16 * 1024 * 1024,
+	25 * 1024 * 1024,
+	32 * 1024 * 1024,
+	50 * 1024 * 1024,
+	64 * 1024 * 1024,
+	100 * 1024 * 1024,
+	128 * 1024 * 1024,
+}
+
+// IntegrityError is returned when the downloaded content doesn't match the checksum published by the API.
+type IntegrityError struct {
+	Algorithm string // Algorithm used for verification, `size`, `sha256`, `content-md5`, `md5` or `md5-multipart`.
+	Expected  string // Checksum (or size) published by the API.
+	Actual    string // Checksum (or size) of the downloaded content.
+}
+
+// Error returns a description of the mismatch.
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("integrity check failed (%s): expected '%s', got '%s'", e.Algorithm, e.Expected, e.Actual)
+}
+
+// VerifyFile verifies the file downloaded earlier against the headers returned by HeadSnapshot or HeadBatch.
+// All of the published checksums (SHA-256, Content-MD5 and MD5 ETag) are verified, multipart ETag is used only if there's none of those.
+// Returns *IntegrityError if the file doesn't match and ErrNoChecksum if there's nothing to verify against.
+func VerifyFile(pth string, hds *schema.Headers) error {
+	fle, err := os.Open(pth)
+
+	if err != nil {
+		return err
+	}
+
+	defer fle.Close()
+	fst, err := fle.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	return verify(fle, fst.Size(), hds)
+}
+
+func verify(rat io.ReaderAt, sze int64, hds *schema.Headers) error {
+	if hds.ContentLength > 0 && int64(hds.ContentLength) != sze {
+		return &IntegrityError{
+			Algorithm: "size",
+			Expected:  strconv.Itoa(hds.ContentLength),
+			Actual:    strconv.FormatInt(sze, 10),
+		}
+	}
+
+	vfd := false
+	hss := []*checksum{}
+
+	if len(hds.ChecksumSHA256) > 0 {
+		hss = append(hss, &checksum{alg: "sha256", exp: hds.ChecksumSHA256, hsr: sha256.New(), enc: base64.StdEncoding.EncodeToString})
+	}
+
+	if len(hds.ContentMD5) > 0 {
+		hss = append(hss, &checksum{alg: "content-md5", exp: hds.ContentMD5, hsr: md5.New(), enc: base64.StdEncoding.EncodeToString})
+	}
+
+	etg := strings.Trim(hds.ETag, "\"")
+
+	if etagMD5.MatchString(etg) {
+		hss = append(hss, &checksum{alg: "md5", exp: etg, hsr: md5.New(), enc: hex.EncodeToString})
+	}
+
+	if len(hss) > 0 {
+		wrs := []io.Writer{}
+
+		for _, chs := range hss {
+			wrs = append(wrs, chs.hsr)
+		}
+
+		if _, err := io.Copy(io.MultiWriter(wrs...), io.NewSectionReader(rat, 0, sze)); err != nil {
+			return err
+		}
+
+		for _, chs := range hss {
+			if err := chs.verify(); err != nil {
+				return err
+			}
+		}
+
+		vfd = true
+	}
+
+	// part size of the multipart ETag is guessed, so it's used only if there's nothing better
+	if mts := etagMultipartMD5.FindStringSubmatch(etg); mts != nil && !vfd {
+		pts, err := strconv.ParseInt(mts[2], 10, 64)
+
+		if err != nil || pts <= 0 {
+			return ErrNoChecksum
+		}
+
+		return verifyMultipart(rat, sze, etg, pts)
+	}
+
+	if !vfd {
+		return ErrNoChecksum
+	}
+
+	return nil
+}
+
+// checksum is a single checksum published by the API.
+type checksum struct {
+	alg string
+	exp string
+	hsr hash.Hash
+	enc func(src []byte) string
+}
+
+func (c *checksum) verify() error {
+	if act := c.enc(c.hsr.Sum(nil)); !strings.EqualFold(act, c.exp) {
+		return &IntegrityError{
+			Algorithm: c.alg,
+			Expected:  c.exp,
+			Actual:    act,
+		}
+	}
+
+	return nil
+}
+
+// multipartHash computes S3-style multipart ETag for a single part size.
+type multipartHash struct {
+	psz int64
+	cur int64
+	prt hash.Hash
+	all hash.Hash
+	cnt int64
+}
+
+func (m *multipartHash) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		lft := m.psz - m.cur
+
+		if int64(len(p)) < lft {
+			lft = int64(len(p))
+		}
+
+		_, _ = m.prt.Write(p[:lft])
+		m.cur += lft
+		p = p[lft:]
+
+		if m.cur == m.psz {
+			m.flush()
+		}
+	}
+
+	return n, nil
+}
+
+func (m *multipartHash) flush() {
+	_, _ = m.all.Write(m.prt.Sum(nil))
+	m.prt.Reset()
+	m.cur = 0
+	m.cnt++
+}
+
+func (m *multipartHash) sum() string {
+	if m.cur > 0 {
+		m.flush()
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(m.all.Sum(nil)), m.cnt)
+}
+
+// verifyMultipart guesses the part size (the ETag doesn't include it) and verifies all of the candidates in a single pass.
+// Returns ErrNoChecksum if none of the guesses matches, as the mismatch can't be told apart from a wrong guess.
+func verifyMultipart(rat io.ReaderAt, sze int64, etg string, pts int64) error {
+	pss := partSizes(sze, pts)
+
+	if len(pss) == 0 {
+		return &IntegrityError{
+			Algorithm: "md5-multipart",
+			Expected:  etg,
+			Actual:    fmt.Sprintf("no part size splits %d bytes into %d parts", sze, pts),
+		}
+	}
+
+	mhs := []*multipartHash{}
+	wrs := []io.Writer{}
+
+	for _, psz := range pss {
+		mhs = append(mhs, &multipartHash{
+			psz: psz,
+			prt: md5.New(),
+			all: md5.New(),
+		})
+		wrs = append(wrs, mhs[len(mhs)-1])
+	}
+
+	if _, err := io.Copy(io.MultiWriter(wrs...), io.NewSectionReader(rat, 0, sze)); err != nil {
+		return err
+	}
+
+	for _, mph := range mhs {
+		if strings.EqualFold(mph.sum(), etg) {
+			return nil
+		}
+	}
+
+	// either the content is corrupted or it was uploaded with a part size we didn't guess
+	return fmt.Errorf("%w: part size of the multipart etag '%s' is unknown", ErrNoChecksum, etg)
+}
+
+func partSizes(sze int64, pts int64) []int64 {
+	fts := func(psz int64) bool {
+		return psz > 0 && (sze+psz-1)/psz == pts
+	}
+
+	pss := []int64{}
+	sen := map[int64]bool{}
+
+	for _, psz := range commonPartSizes {
+		if fts(psz) && !sen[psz] {
+			pss = append(pss, psz)
+			sen[psz] = true
+		}
+	}
+
+	mib := int64(1024 * 1024)
+
+	for _, psz := range []int64{
+		(sze + pts - 1) / pts,
+		((sze+pts-1)/pts + mib - 1) / mib * mib,
+	} {
+		if fts(psz) && !sen[psz] {
+			pss = append(pss, psz)
+			sen[psz] = true
+		}
+	}
+
+	return pss
+}
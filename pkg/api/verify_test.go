@@ -0,0 +1,206 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+func createMultipartETag(dta []byte, psz int) string {
+	all := []byte{}
+	pts := 0
+
+	for i := 0; i < len(dta); i += psz {
+		end := i + psz
+
+		if end > len(dta) {
+			end = len(dta)
+		}
+
+		sum := md5.Sum(dta[i:end])
+		all = append(all, sum[:]...)
+		pts++
+	}
+
+	sum := md5.Sum(all)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), pts)
+}
+
+type verifyTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	dta []byte
+	etg string
+	cmd string
+	sha string
+	sze int
+	err error
+	ier bool
+}
+
+func (s *verifyTestSuite) SetupSuite() {
+	s.ctx = context.Background()
+}
+
+func (s *verifyTestSuite) TestVerifyFile() {
+	pth := filepath.Join(s.T().TempDir(), "enwiki_namespace_0.tar.gz")
+	s.Assert().NoError(os.WriteFile(pth, s.dta, 0644))
+
+	err := api.VerifyFile(pth, &schema.Headers{
+		ETag:           s.etg,
+		ContentLength:  s.sze,
+		ContentMD5:     s.cmd,
+		ChecksumSHA256: s.sha,
+	})
+
+	s.assertError(err)
+}
+
+func (s *verifyTestSuite) TestDownloadSnapshot() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, s.etg))
+
+		if len(s.cmd) > 0 {
+			w.Header().Set("Content-MD5", s.cmd)
+		}
+
+		if len(s.sha) > 0 {
+			w.Header().Set("X-Amz-Checksum-Sha256", s.sha)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.dta))
+	}))
+	defer srv.Close()
+
+	clt := api.NewClient(func(clt *api.Client) {
+		clt.BaseUrl = fmt.Sprintf("%s/", srv.URL)
+		clt.DownloadVerify = true
+	})
+
+	fle, err := os.Create(filepath.Join(s.T().TempDir(), "enwiki_namespace_0.tar.gz"))
+	s.Assert().NoError(err)
+	defer fle.Close()
+
+	err = clt.DownloadSnapshot(s.ctx, "enwiki_namespace_0", fle)
+
+	if s.sze != len(s.dta) {
+		s.T().Skip("server always returns the real size")
+	}
+
+	s.assertError(err)
+}
+
+func (s *verifyTestSuite) assertError(err error) {
+	if s.ier {
+		ier := new(api.IntegrityError)
+		s.Assert().True(errors.As(err, &ier))
+		return
+	}
+
+	if s.err != nil {
+		s.Assert().ErrorIs(err, s.err)
+		return
+	}
+
+	s.Assert().NoError(err)
+}
+
+func TestVerify(t *testing.T) {
+	dta := bytes.Repeat([]byte("0123456789abcdef"), 400000)
+	sum := md5.Sum(dta)
+	etg := hex.EncodeToString(sum[:])
+	cmd := base64.StdEncoding.EncodeToString(sum[:])
+	shs := sha256.Sum256(dta)
+	sha := base64.StdEncoding.EncodeToString(shs[:])
+
+	for _, testCase := range []*verifyTestSuite{
+		{
+			dta: dta,
+			etg: etg,
+			sze: len(dta),
+		},
+		{
+			dta: dta,
+			etg: "00000000000000000000000000000000",
+			sze: len(dta),
+			ier: true,
+		},
+		{
+			dta: dta,
+			etg: createMultipartETag(dta, 5*1024*1024),
+			sze: len(dta),
+		},
+		{
+			dta: dta,
+			etg: createMultipartETag(dta, 1024*1024),
+			sze: len(dta),
+		},
+		{
+			dta: dta,
+			etg: "00000000000000000000000000000000-2",
+			sze: len(dta),
+			err: api.ErrNoChecksum,
+		},
+		{
+			dta: dta,
+			etg: "00000000000000000000000000000000-2",
+			sha: sha,
+			sze: len(dta),
+		},
+		{
+			dta: dta,
+			etg: "W/version",
+			sha: sha,
+			cmd: cmd,
+			sze: len(dta),
+		},
+		{
+			dta: dta,
+			etg: "W/version",
+			cmd: cmd,
+			sze: len(dta),
+		},
+		{
+			dta: dta,
+			etg: etg,
+			sha: base64.StdEncoding.EncodeToString(make([]byte, 32)),
+			sze: len(dta),
+			ier: true,
+		},
+		{
+			dta: dta,
+			etg: "W/version",
+			cmd: base64.StdEncoding.EncodeToString(make([]byte, 16)),
+			sze: len(dta),
+			ier: true,
+		},
+		{
+			dta: dta,
+			etg: etg,
+			sze: len(dta) + 1,
+			ier: true,
+		},
+		{
+			dta: dta,
+			etg: "W/version",
+			sze: len(dta),
+			err: api.ErrNoChecksum,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
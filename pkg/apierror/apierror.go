@@ -0,0 +1,170 @@
+// Package apierror holds an error type shared by all of the WME clients.
+// Lets you tell apart different error responses without matching the error strings.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error represents unsuccessful response from one of the WME APIs.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"status_code"`
+
+	// Status is the HTTP status of the response, for example "404 Not Found".
+	Status string `json:"status"`
+
+	// Method is the HTTP method of the request.
+	Method string `json:"method,omitempty"`
+
+	// Path is the URL path of the request.
+	Path string `json:"path,omitempty"`
+
+	// Body is the raw body of the response.
+	Body []byte `json:"body,omitempty"`
+
+	// Message is the message parsed from the JSON body, empty if the body is not a JSON.
+	Message string `json:"message,omitempty"`
+
+	// RetryAfter is the value of Retry-After header, zero if it was not present.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+}
+
+// New creates an error from the response and reads the response body.
+// Closing the body is left to the caller.
+// Returns the read error if the body can't be read.
+func New(res *http.Response) error {
+	dta, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return err
+	}
+
+	aer := &Error{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Body:       dta,
+		RetryAfter: ParseRetryAfter(res.Header.Get("Retry-After")),
+	}
+
+	if res.Request != nil {
+		aer.Method = res.Request.Method
+
+		if res.Request.URL != nil {
+			aer.Path = res.Request.URL.Path
+		}
+	}
+
+	msg := new(struct {
+		Message string `json:"message"`
+	})
+
+	if err := json.Unmarshal(dta, msg); err == nil {
+		aer.Message = msg.Message
+	}
+
+	return aer
+}
+
+// ParseRetryAfter parses the value of the Retry-After header,
+// supports both delay in seconds and HTTP date formats.
+func ParseRetryAfter(val string) time.Duration {
+	val = strings.TrimSpace(val)
+
+	if len(val) == 0 {
+		return 0
+	}
+
+	if sec, err := strconv.Atoi(val); err == nil {
+		if sec < 0 {
+			return 0
+		}
+
+		return time.Duration(sec) * time.Second
+	}
+
+	if dte, err := http.ParseTime(val); err == nil {
+		if dur := time.Until(dte); dur > 0 {
+			return dur
+		}
+	}
+
+	return 0
+}
+
+// Error returns the status and the body of the response.
+func (e *Error) Error() string {
+	if len(e.Body) == 0 {
+		return e.Status
+	}
+
+	return fmt.Sprintf("%s: %s", e.Status, string(e.Body))
+}
+
+// IsNotFound checks if the response status is 404.
+func (e *Error) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsUnauthorized checks if the response status is 401.
+func (e *Error) IsUnauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// IsRateLimited checks if the response status is 429.
+func (e *Error) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsTemporary checks if the request can be retried,
+// true for rate limiting, timeouts and server side errors.
+func (e *Error) IsTemporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// As finds the first *Error in the error chain.
+func As(err error) (*Error, bool) {
+	aer := new(Error)
+	return aer, errors.As(err, &aer)
+}
+
+// IsNotFound checks if the error is an API error with 404 status.
+func IsNotFound(err error) bool {
+	aer, ok := As(err)
+	return ok && aer.IsNotFound()
+}
+
+// IsUnauthorized checks if the error is an API error with 401 status.
+func IsUnauthorized(err error) bool {
+	aer, ok := As(err)
+	return ok && aer.IsUnauthorized()
+}
+
+// IsRateLimited checks if the error is an API error with 429 status.
+func IsRateLimited(err error) bool {
+	aer, ok := As(err)
+	return ok && aer.IsRateLimited()
+}
+
+// IsTemporary checks if the error is an API error that can be retried.
+func IsTemporary(err error) bool {
+	aer, ok := As(err)
+	return ok && aer.IsTemporary()
+}
@@ -0,0 +1,104 @@
+package apierror_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/stretchr/testify/suite"
+)
+
+type apiErrorTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	sts int
+	bdy string
+	rta string
+	msg string
+	dur time.Duration
+	nfd bool
+	uth bool
+	rtl bool
+	tmp bool
+}
+
+func (s *apiErrorTestSuite) SetupSuite() {
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.rta) > 0 {
+			w.Header().Set("Retry-After", s.rta)
+		}
+
+		w.WriteHeader(s.sts)
+		_, _ = w.Write([]byte(s.bdy))
+	}))
+}
+
+func (s *apiErrorTestSuite) TearDownSuite() {
+	s.srv.Close()
+}
+
+func (s *apiErrorTestSuite) TestNew() {
+	res, err := http.Get(fmt.Sprintf("%s/v2/articles/Earth", s.srv.URL))
+	s.Assert().NoError(err)
+	defer res.Body.Close()
+
+	err = fmt.Errorf("wrapped: %w", apierror.New(res))
+
+	aer, ok := apierror.As(err)
+	s.Assert().True(ok)
+	s.Assert().Equal(s.sts, aer.StatusCode)
+	s.Assert().Equal(http.MethodGet, aer.Method)
+	s.Assert().Equal("/v2/articles/Earth", aer.Path)
+	s.Assert().Equal(s.bdy, string(aer.Body))
+	s.Assert().Equal(s.msg, aer.Message)
+	s.Assert().Equal(s.dur, aer.RetryAfter)
+	s.Assert().Contains(err.Error(), http.StatusText(s.sts))
+	s.Assert().Equal(s.nfd, apierror.IsNotFound(err))
+	s.Assert().Equal(s.uth, apierror.IsUnauthorized(err))
+	s.Assert().Equal(s.rtl, apierror.IsRateLimited(err))
+	s.Assert().Equal(s.tmp, apierror.IsTemporary(err))
+}
+
+func TestAPIError(t *testing.T) {
+	for _, testCase := range []*apiErrorTestSuite{
+		{
+			sts: http.StatusNotFound,
+			bdy: `{"status":404,"message":"Not found."}`,
+			msg: "Not found.",
+			nfd: true,
+		},
+		{
+			sts: http.StatusUnauthorized,
+			uth: true,
+		},
+		{
+			sts: http.StatusTooManyRequests,
+			bdy: "slow down",
+			rta: "10",
+			dur: time.Second * 10,
+			rtl: true,
+			tmp: true,
+		},
+		{
+			sts: http.StatusServiceUnavailable,
+			tmp: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+func TestIsNotAPIError(t *testing.T) {
+	err := fmt.Errorf("not an api error")
+
+	if _, ok := apierror.As(err); ok {
+		t.Error("expected not to find api error")
+	}
+
+	if apierror.IsTemporary(err) || apierror.IsNotFound(err) {
+		t.Error("expected helpers to return false")
+	}
+}
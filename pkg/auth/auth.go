@@ -5,8 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+
+	"github.com/protsack-stephan/wme/pkg/apierror"
 )
 
 // LoginRequest parameters required for login request.
@@ -76,14 +77,8 @@ func (c *Client) post(ctx context.Context, url string, v interface{}) (*http.Res
 	}
 
 	if res.StatusCode < http.StatusOK || res.StatusCode > http.StatusIMUsed {
-		data, err := io.ReadAll(res.Body)
-
-		if err != nil {
-			return nil, err
-		}
-
 		defer res.Body.Close()
-		return nil, fmt.Errorf("%s: %s", res.Status, string(data))
+		return nil, apierror.New(res)
 	}
 
 	return res, nil
@@ -126,6 +121,7 @@ func (c *Client) RefreshToken(ctx context.Context, req *RefreshTokenRequest) (*R
 }
 
 // RevokeToken invalidates refresh token and all related access tokens.
+// Sessions cached by the TokenSource are not cleared, use TokenSource.RevokeToken for those.
 func (c *Client) RevokeToken(ctx context.Context, req *RevokeTokenRequest) error {
 	_, err := c.post(ctx, "/token-revoke", req)
 	return err
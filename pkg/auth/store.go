@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/wme/internal/atomicfile"
+)
+
+// ErrSessionNotFound is returned by the token store when there's no session for the key.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a login response with absolute expiry time, used to persist tokens.
+type Session struct {
+	LoginResponse
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IsValid checks if the access token is still valid for at least the provided duration.
+func (s *Session) IsValid(dur time.Duration) bool {
+	return len(s.AccessToken) > 0 && time.Now().Add(dur).Before(s.ExpiresAt)
+}
+
+// TokenStore is an interface for persisting sessions between process restarts.
+type TokenStore interface {
+	Get(ctx context.Context, key string) (*Session, error)
+	Put(ctx context.Context, key string, ses *Session) error
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFileStore creates new file based token store, by default the file is readable only by the owner.
+func NewFileStore(pth string) *FileStore {
+	return &FileStore{
+		Path: pth,
+		Perm: 0600,
+	}
+}
+
+// FileStore is a token store that keeps sessions in a single JSON file.
+type FileStore struct {
+	Path string
+	Perm os.FileMode
+	mut  sync.Mutex
+}
+
+// Get returns the session for the key, or ErrSessionNotFound if there's none.
+func (f *FileStore) Get(_ context.Context, key string) (*Session, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	sss, err := f.read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ses, ok := sss[key]
+
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return ses, nil
+}
+
+// Put saves the session for the key.
+func (f *FileStore) Put(_ context.Context, key string, ses *Session) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	sss, err := f.read()
+
+	if err != nil {
+		return err
+	}
+
+	sss[key] = ses
+	return f.write(sss)
+}
+
+// Delete removes the session for the key.
+func (f *FileStore) Delete(_ context.Context, key string) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	sss, err := f.read()
+
+	if err != nil {
+		return err
+	}
+
+	delete(sss, key)
+	return f.write(sss)
+}
+
+func (f *FileStore) read() (map[string]*Session, error) {
+	sss := map[string]*Session{}
+	dta, err := os.ReadFile(f.Path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return sss, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dta) == 0 {
+		return sss, nil
+	}
+
+	return sss, json.Unmarshal(dta, &sss)
+}
+
+func (f *FileStore) write(sss map[string]*Session) error {
+	dta, err := json.Marshal(sss)
+
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(f.Path, dta, f.Perm)
+}
@@ -0,0 +1,70 @@
+package auth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/stretchr/testify/suite"
+)
+
+type fileStoreTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	fst *auth.FileStore
+	key string
+	ses *auth.Session
+}
+
+func (s *fileStoreTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.fst = auth.NewFileStore(filepath.Join(s.T().TempDir(), "wme", "tokens.json"))
+}
+
+func (s *fileStoreTestSuite) TestGetNotFound() {
+	ses, err := s.fst.Get(s.ctx, s.key)
+
+	s.Assert().ErrorIs(err, auth.ErrSessionNotFound)
+	s.Assert().Nil(ses)
+}
+
+func (s *fileStoreTestSuite) TestPutGetDelete() {
+	s.Assert().NoError(s.fst.Put(s.ctx, s.key, s.ses))
+
+	fst, err := os.Stat(s.fst.Path)
+	s.Assert().NoError(err)
+	s.Assert().Equal(os.FileMode(0600), fst.Mode().Perm())
+
+	ses, err := auth.NewFileStore(s.fst.Path).Get(s.ctx, s.key)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.ses.RefreshToken, ses.RefreshToken)
+	s.Assert().Equal(s.ses.AccessToken, ses.AccessToken)
+	s.Assert().True(s.ses.ExpiresAt.Equal(ses.ExpiresAt))
+
+	s.Assert().NoError(s.fst.Delete(s.ctx, s.key))
+
+	_, err = s.fst.Get(s.ctx, s.key)
+	s.Assert().ErrorIs(err, auth.ErrSessionNotFound)
+}
+
+func TestFileStore(t *testing.T) {
+	for _, testCase := range []*fileStoreTestSuite{
+		{
+			key: "test",
+			ses: &auth.Session{
+				LoginResponse: auth.LoginResponse{
+					IDToken:      "id_token",
+					AccessToken:  "access_token",
+					RefreshToken: "refresh_token",
+					ExpiresIn:    3600,
+				},
+				ExpiresAt: time.Now().Add(time.Hour),
+			},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
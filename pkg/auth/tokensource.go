@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/apierror"
+)
+
+// TokenGetter is an interface for anything that can provide a valid access token.
+type TokenGetter interface {
+	GetToken(ctx context.Context) (string, error)
+}
+
+// NewTokenSource creates new token source that will login with provided credentials
+// and keep the access token fresh for the lifetime of the process.
+func NewTokenSource(clt *Client, req *LoginRequest) *TokenSource {
+	return &TokenSource{
+		Client:        clt,
+		Credentials:   req,
+		RefreshBefore: time.Minute,
+	}
+}
+
+// TokenSource keeps track of the access token and its expiry.
+// Refreshes the token ahead of the expiry and falls back
+// to a fresh login if the refresh token was rejected (400 or 401).
+// If the store is set, the session will be reused between process restarts.
+// Safe for concurrent use, so it can be shared between clients.
+type TokenSource struct {
+	Client        *Client
+	Credentials   *LoginRequest
+	Store         TokenStore    // Optional store to persist the session, keyed by username.
+	RefreshBefore time.Duration // Amount of time before the expiry when token will be refreshed.
+	mut           sync.Mutex
+	ses           *Session
+}
+
+// GetToken returns valid access token, logs in or refreshes the token if needed.
+func (t *TokenSource) GetToken(ctx context.Context) (string, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if err := t.load(ctx); err != nil {
+		return "", err
+	}
+
+	if t.ses != nil && t.ses.IsValid(t.RefreshBefore) {
+		return t.ses.AccessToken, nil
+	}
+
+	if err := t.renew(ctx); err != nil {
+		return "", err
+	}
+
+	return t.ses.AccessToken, nil
+}
+
+// Refresh forces the access token refresh regardless of the expiry time.
+func (t *TokenSource) Refresh(ctx context.Context) (string, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if err := t.renew(ctx); err != nil {
+		return "", err
+	}
+
+	return t.ses.AccessToken, nil
+}
+
+// RefreshIfCurrent refreshes the access token only if the source still holds the old (rejected) one,
+// otherwise the current token is returned, so the requests rejected at the same time refresh it once.
+func (t *TokenSource) RefreshIfCurrent(ctx context.Context, old string) (string, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if err := t.load(ctx); err != nil {
+		return "", err
+	}
+
+	if t.ses != nil && t.ses.AccessToken != old && t.ses.IsValid(0) {
+		return t.ses.AccessToken, nil
+	}
+
+	if err := t.renew(ctx); err != nil {
+		return "", err
+	}
+
+	return t.ses.AccessToken, nil
+}
+
+// RevokeToken invalidates the refresh token and clears the session, including the stored one.
+// Use it instead of Client.RevokeToken when the token source is in use, so the revoked session is not reused.
+func (t *TokenSource) RevokeToken(ctx context.Context) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if err := t.load(ctx); err != nil {
+		return err
+	}
+
+	if t.ses != nil && len(t.ses.RefreshToken) > 0 {
+		err := t.Client.RevokeToken(ctx, &RevokeTokenRequest{
+			RefreshToken: t.ses.RefreshToken,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	t.ses = nil
+
+	if t.Store != nil {
+		return t.Store.Delete(ctx, t.Credentials.Username)
+	}
+
+	return nil
+}
+
+// load reads the session from the store, unless it's already loaded.
+func (t *TokenSource) load(ctx context.Context) error {
+	if t.ses != nil || t.Store == nil {
+		return nil
+	}
+
+	ses, err := t.Store.Get(ctx, t.Credentials.Username)
+
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+
+	t.ses = ses
+	return nil
+}
+
+func (t *TokenSource) renew(ctx context.Context) error {
+	if t.ses != nil && len(t.ses.RefreshToken) > 0 {
+		err := t.refresh(ctx)
+
+		if err == nil {
+			return t.save(ctx)
+		}
+
+		// only a rejected refresh token needs a new login, network and server errors are returned as is
+		aer := new(apierror.Error)
+
+		if !errors.As(err, &aer) || (aer.StatusCode != http.StatusUnauthorized && aer.StatusCode != http.StatusBadRequest) {
+			return err
+		}
+	}
+
+	if err := t.login(ctx); err != nil {
+		return err
+	}
+
+	return t.save(ctx)
+}
+
+func (t *TokenSource) refresh(ctx context.Context) error {
+	rsp, err := t.Client.RefreshToken(ctx, &RefreshTokenRequest{
+		Username:     t.Credentials.Username,
+		RefreshToken: t.ses.RefreshToken,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	t.ses = &Session{
+		LoginResponse: LoginResponse{
+			IDToken:      rsp.IDToken,
+			AccessToken:  rsp.AccessToken,
+			RefreshToken: t.ses.RefreshToken,
+			ExpiresIn:    rsp.ExpiresIn,
+		},
+		ExpiresAt: time.Now().Add(time.Duration(rsp.ExpiresIn) * time.Second),
+	}
+
+	return nil
+}
+
+func (t *TokenSource) login(ctx context.Context) error {
+	rsp, err := t.Client.Login(ctx, t.Credentials)
+
+	if err != nil {
+		return err
+	}
+
+	t.ses = &Session{
+		LoginResponse: *rsp,
+		ExpiresAt:     time.Now().Add(time.Duration(rsp.ExpiresIn) * time.Second),
+	}
+
+	return nil
+}
+
+func (t *TokenSource) save(ctx context.Context) error {
+	if t.Store == nil {
+		return nil
+	}
+
+	return t.Store.Put(ctx, t.Credentials.Username, t.ses)
+}
@@ -0,0 +1,246 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/stretchr/testify/suite"
+)
+
+type tokenSourceTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	tks *auth.TokenSource
+	ctx context.Context
+	req *auth.LoginRequest
+	lgn *auth.LoginResponse
+	rft *auth.RefreshTokenResponse
+	rfs int
+	rbf time.Duration
+	tkn []string
+	elc int
+	erc int
+	lgc int
+	rfc int
+	rvk []string
+}
+
+func (s *tokenSourceTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	s.lgc = 0
+	s.rfc = 0
+	s.rvk = nil
+
+	rtr := gin.New()
+	rtr.POST("/login", func(gcx *gin.Context) {
+		s.lgc++
+		gcx.JSON(http.StatusOK, s.lgn)
+	})
+	rtr.POST("/token-revoke", func(gcx *gin.Context) {
+		req := new(auth.RevokeTokenRequest)
+		_ = gcx.BindJSON(req)
+
+		s.rvk = append(s.rvk, req.RefreshToken)
+		gcx.Status(http.StatusOK)
+	})
+	rtr.POST("/token-refresh", func(gcx *gin.Context) {
+		s.rfc++
+
+		if s.rfs != http.StatusOK {
+			gcx.JSON(s.rfs, gin.H{"message": "refresh token rejected"})
+			return
+		}
+
+		gcx.JSON(http.StatusOK, s.rft)
+	})
+
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(rtr)
+	s.tks = auth.NewTokenSource(&auth.Client{
+		HTTPClient: &http.Client{},
+		BaseURL:    s.srv.URL,
+	}, s.req)
+	s.tks.RefreshBefore = s.rbf
+}
+
+func (s *tokenSourceTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *tokenSourceTestSuite) TestGetToken() {
+	for _, tkn := range s.tkn {
+		res, err := s.tks.GetToken(s.ctx)
+
+		// empty token means the refresh error is expected to be returned
+		if len(tkn) == 0 {
+			s.Assert().Error(err)
+		} else {
+			s.Assert().NoError(err)
+		}
+
+		s.Assert().Equal(tkn, res)
+	}
+
+	s.Assert().Equal(s.elc, s.lgc)
+	s.Assert().Equal(s.erc, s.rfc)
+}
+
+func (s *tokenSourceTestSuite) TestGetTokenFromStore() {
+	fst := auth.NewFileStore(filepath.Join(s.T().TempDir(), "tokens.json"))
+	s.tks.Store = fst
+	s.tks.RefreshBefore = 0
+
+	_, err := s.tks.GetToken(s.ctx)
+	s.Assert().NoError(err)
+
+	ses, err := fst.Get(s.ctx, s.req.Username)
+	s.Assert().NoError(err)
+	s.Assert().True(ses.IsValid(0))
+
+	// stored session is reused without login or refresh
+	tks := auth.NewTokenSource(s.tks.Client, s.req)
+	tks.RefreshBefore = 0
+	tks.Store = fst
+
+	res, err := tks.GetToken(s.ctx)
+	s.Assert().NoError(err)
+	s.Assert().Equal(ses.AccessToken, res)
+	s.Assert().Equal(1, s.lgc)
+	s.Assert().Equal(0, s.rfc)
+
+	// stored refresh token is revoked even if the session was never loaded
+	tks = auth.NewTokenSource(s.tks.Client, s.req)
+	tks.Store = fst
+
+	s.Assert().NoError(tks.RevokeToken(s.ctx))
+	s.Assert().Equal([]string{ses.RefreshToken}, s.rvk)
+
+	_, err = fst.Get(s.ctx, s.req.Username)
+	s.Assert().ErrorIs(err, auth.ErrSessionNotFound)
+}
+
+func (s *tokenSourceTestSuite) TestRefresh() {
+	_, err := s.tks.GetToken(s.ctx)
+	s.Assert().NoError(err)
+
+	res, err := s.tks.Refresh(s.ctx)
+
+	switch s.rfs {
+	case http.StatusOK:
+		s.Assert().NoError(err)
+		s.Assert().Equal(s.rft.AccessToken, res)
+		s.Assert().Equal(1, s.lgc)
+	case http.StatusUnauthorized, http.StatusBadRequest:
+		s.Assert().NoError(err)
+		s.Assert().Equal(s.lgn.AccessToken, res)
+		s.Assert().Equal(2, s.lgc)
+	default:
+		s.Assert().Error(err)
+		s.Assert().Empty(res)
+		s.Assert().Equal(1, s.lgc)
+	}
+
+	s.Assert().Equal(1, s.rfc)
+}
+
+func TestTokenSource(t *testing.T) {
+	for _, testCase := range []*tokenSourceTestSuite{
+		{
+			req: &auth.LoginRequest{
+				Username: "test",
+				Password: "password",
+			},
+			lgn: &auth.LoginResponse{
+				AccessToken:  "login_access_token",
+				RefreshToken: "refresh_token",
+				ExpiresIn:    3600,
+			},
+			rft: &auth.RefreshTokenResponse{
+				AccessToken: "refreshed_access_token",
+				ExpiresIn:   3600,
+			},
+			rfs: http.StatusOK,
+			rbf: time.Minute,
+			tkn: []string{"login_access_token", "login_access_token"},
+			elc: 1,
+			erc: 0,
+		},
+		{
+			req: &auth.LoginRequest{
+				Username: "test",
+				Password: "password",
+			},
+			lgn: &auth.LoginResponse{
+				AccessToken:  "login_access_token",
+				RefreshToken: "refresh_token",
+				ExpiresIn:    30,
+			},
+			rft: &auth.RefreshTokenResponse{
+				AccessToken: "refreshed_access_token",
+				ExpiresIn:   30,
+			},
+			rfs: http.StatusOK,
+			rbf: time.Minute,
+			tkn: []string{"login_access_token", "refreshed_access_token"},
+			elc: 1,
+			erc: 1,
+		},
+		{
+			req: &auth.LoginRequest{
+				Username: "test",
+				Password: "password",
+			},
+			lgn: &auth.LoginResponse{
+				AccessToken:  "login_access_token",
+				RefreshToken: "refresh_token",
+				ExpiresIn:    30,
+			},
+			rfs: http.StatusUnauthorized,
+			rbf: time.Minute,
+			tkn: []string{"login_access_token", "login_access_token"},
+			elc: 2,
+			erc: 1,
+		},
+		{
+			req: &auth.LoginRequest{
+				Username: "test",
+				Password: "password",
+			},
+			lgn: &auth.LoginResponse{
+				AccessToken:  "login_access_token",
+				RefreshToken: "refresh_token",
+				ExpiresIn:    30,
+			},
+			rfs: http.StatusBadRequest,
+			rbf: time.Minute,
+			tkn: []string{"login_access_token", "login_access_token"},
+			elc: 2,
+			erc: 1,
+		},
+		{
+			req: &auth.LoginRequest{
+				Username: "test",
+				Password: "password",
+			},
+			lgn: &auth.LoginResponse{
+				AccessToken:  "login_access_token",
+				RefreshToken: "refresh_token",
+				ExpiresIn:    30,
+			},
+			rfs: http.StatusInternalServerError,
+			rbf: time.Minute,
+			tkn: []string{"login_access_token", ""},
+			elc: 1,
+			erc: 1,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
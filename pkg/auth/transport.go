@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// TokenRefresher is an interface for token sources that can be forced to refresh the token.
+// RefreshIfCurrent refreshes only if the source still holds the rejected token, otherwise returns the current one.
+type TokenRefresher interface {
+	TokenGetter
+	Refresh(ctx context.Context) (string, error)
+	RefreshIfCurrent(ctx context.Context, old string) (string, error)
+}
+
+// NewHTTPClient creates new http client that authenticates every request with the token from the source.
+func NewHTTPClient(src TokenRefresher) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(src, nil),
+	}
+}
+
+// NewTransport creates new round tripper on top of the base transport,
+// if base is nil http.DefaultTransport will be used.
+func NewTransport(src TokenRefresher, base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:   base,
+		Source: src,
+	}
+}
+
+// Transport is an http.RoundTripper that sets bearer token on each request.
+// On 401 response refreshes the token and retries the request once,
+// requests rejected concurrently with the same token trigger a single refresh.
+// Note that it should not be used as a transport for the auth client itself.
+type Transport struct {
+	Base   http.RoundTripper
+	Source TokenRefresher
+}
+
+// RoundTrip executes a single HTTP transaction with the authorization header set.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tkn, err := t.Source.GetToken(req.Context())
+
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	res, err := t.base().RoundTrip(authorize(req, tkn))
+
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+
+	tkn, err = t.Source.RefreshIfCurrent(req.Context(), tkn)
+
+	if err != nil {
+		return res, nil
+	}
+
+	rrq := authorize(req, tkn)
+
+	if req.GetBody != nil {
+		bdy, err := req.GetBody()
+
+		if err != nil {
+			return res, nil
+		}
+
+		rrq.Body = bdy
+	}
+
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	return t.base().RoundTrip(rrq)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+
+	return http.DefaultTransport
+}
+
+func authorize(req *http.Request, tkn string) *http.Request {
+	arq := req.Clone(req.Context())
+	arq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tkn))
+	return arq
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
@@ -0,0 +1,210 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/stretchr/testify/suite"
+)
+
+type tokenRefresher struct {
+	tkn string
+	rft string
+	rfc int
+}
+
+func (t *tokenRefresher) GetToken(_ context.Context) (string, error) {
+	return t.tkn, nil
+}
+
+func (t *tokenRefresher) Refresh(_ context.Context) (string, error) {
+	t.rfc++
+	t.tkn = t.rft
+	return t.tkn, nil
+}
+
+func (t *tokenRefresher) RefreshIfCurrent(ctx context.Context, old string) (string, error) {
+	if old != t.tkn {
+		return t.tkn, nil
+	}
+
+	return t.Refresh(ctx)
+}
+
+type transportTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	src *tokenRefresher
+	hcl *http.Client
+	vld string
+	bdy string
+	sts int
+	rfc int
+	cls int
+}
+
+func (s *transportTestSuite) SetupTest() {
+	s.cls = 0
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.cls++
+		bdy, _ := io.ReadAll(r.Body)
+		s.Assert().Equal(s.bdy, string(bdy))
+
+		if r.Header.Get("Authorization") != "Bearer "+s.vld {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.hcl = auth.NewHTTPClient(s.src)
+}
+
+func (s *transportTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *transportTestSuite) TestRoundTrip() {
+	req, err := http.NewRequest(http.MethodPost, s.srv.URL, bytes.NewReader([]byte(s.bdy)))
+	s.Assert().NoError(err)
+
+	res, err := s.hcl.Do(req)
+	s.Assert().NoError(err)
+	defer res.Body.Close()
+
+	s.Assert().Equal(s.sts, res.StatusCode)
+	s.Assert().Equal(s.rfc, s.src.rfc)
+	s.Assert().Equal(s.rfc+1, s.cls)
+}
+
+type concurrentTransportTestSuite struct {
+	suite.Suite
+	ath *httptest.Server
+	srv *httptest.Server
+	tks *auth.TokenSource
+	hcl *http.Client
+	rqs int
+	rfc int32
+}
+
+func (s *concurrentTransportTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	atomic.StoreInt32(&s.rfc, 0)
+
+	// the access token from the login was revoked, only the refreshed one is accepted
+	rtr := gin.New()
+	rtr.POST("/login", func(gcx *gin.Context) {
+		gcx.JSON(http.StatusOK, &auth.LoginResponse{
+			AccessToken:  "revoked",
+			RefreshToken: "refresh_token",
+			ExpiresIn:    3600,
+		})
+	})
+	rtr.POST("/token-refresh", func(gcx *gin.Context) {
+		atomic.AddInt32(&s.rfc, 1)
+		gcx.JSON(http.StatusOK, &auth.RefreshTokenResponse{
+			AccessToken: "valid",
+			ExpiresIn:   3600,
+		})
+	})
+
+	s.ath = httptest.NewServer(rtr)
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.tks = auth.NewTokenSource(&auth.Client{
+		HTTPClient: &http.Client{},
+		BaseURL:    s.ath.URL,
+	}, &auth.LoginRequest{Username: "test", Password: "password"})
+	s.hcl = auth.NewHTTPClient(s.tks)
+}
+
+func (s *concurrentTransportTestSuite) TearDownTest() {
+	s.srv.Close()
+	s.ath.Close()
+}
+
+func (s *concurrentTransportTestSuite) TestRoundTrip() {
+	// all of the requests get the revoked token before any of them is rejected
+	_, err := s.tks.GetToken(context.Background())
+	s.Require().NoError(err)
+
+	wgp := new(sync.WaitGroup)
+	sts := make(chan int, s.rqs)
+
+	for i := 0; i < s.rqs; i++ {
+		wgp.Add(1)
+
+		go func() {
+			defer wgp.Done()
+
+			res, err := s.hcl.Get(s.srv.URL)
+
+			if err != nil {
+				sts <- 0
+				return
+			}
+
+			defer res.Body.Close()
+			sts <- res.StatusCode
+		}()
+	}
+
+	wgp.Wait()
+	close(sts)
+
+	for stc := range sts {
+		s.Assert().Equal(http.StatusOK, stc)
+	}
+
+	s.Assert().Equal(int32(1), atomic.LoadInt32(&s.rfc))
+}
+
+func TestConcurrentTransport(t *testing.T) {
+	for _, testCase := range []*concurrentTransportTestSuite{
+		{rqs: 1},
+		{rqs: 10},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	for _, testCase := range []*transportTestSuite{
+		{
+			src: &tokenRefresher{tkn: "valid", rft: "valid"},
+			vld: "valid",
+			bdy: `{"name":"Earth"}`,
+			sts: http.StatusOK,
+			rfc: 0,
+		},
+		{
+			src: &tokenRefresher{tkn: "expired", rft: "valid"},
+			vld: "valid",
+			bdy: `{"name":"Earth"}`,
+			sts: http.StatusOK,
+			rfc: 1,
+		},
+		{
+			src: &tokenRefresher{tkn: "expired", rft: "revoked"},
+			vld: "valid",
+			sts: http.StatusUnauthorized,
+			rfc: 1,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,300 @@
+// Package builder creates api.Request and realtime.ArticlesRequest with fields and filters
+// validated against the schema, so typos like `in_language.identifer` fail early instead of silently returning nothing.
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/filter"
+	"github.com/protsack-stephan/wme/pkg/realtime"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// ErrUnknownField is returned when the field doesn't exist in the schema.
+var ErrUnknownField = errors.New("builder: unknown field")
+
+// ErrInvalidValue is returned when the filter value doesn't match the type of the field.
+var ErrInvalidValue = errors.New("builder: invalid value")
+
+// ErrNotArticles is returned when realtime request is built for anything other than schema.Article.
+var ErrNotArticles = errors.New("builder: realtime requests are available only for articles")
+
+// FieldError describes the field that failed the validation.
+type FieldError struct {
+	Field string // Field as it was passed to the builder.
+	Err   error  // ErrUnknownField or ErrInvalidValue.
+	Msg   string // Details about the failure.
+}
+
+// Error returns the error message.
+func (e *FieldError) Error() string {
+	if len(e.Msg) == 0 {
+		return fmt.Sprintf("%s: %s", e.Err, e.Field)
+	}
+
+	return fmt.Sprintf("%s: %s, %s", e.Err, e.Field, e.Msg)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Builder collects the fields and filters for a single schema type.
+// The first validation error is kept and returned when the request is built, so the calls can be chained.
+type Builder struct {
+	typ reflect.Type
+	fds []string
+	fts []*filter.Filter
+	snc *time.Time
+	lmt int
+	pts []int
+	ofs map[int]int64
+	spp map[int]time.Time
+	err error
+}
+
+// New creates a builder for the schema type, for example schema.Article{}, schema.Thing{}, schema.Snapshot{} or schema.Batch{}.
+func New(ent interface{}) *Builder {
+	typ := reflect.TypeOf(ent)
+
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	bdr := &Builder{typ: typ}
+
+	if typ == nil || typ.Kind() != reflect.Struct {
+		bdr.err = fmt.Errorf("builder: %v is not a struct", typ)
+	}
+
+	return bdr
+}
+
+// Fields adds the fields to retrieve, `*` at the end of the path selects all of the nested fields (for example `event.*`).
+func (b *Builder) Fields(fds ...string) *Builder {
+	for _, fld := range fds {
+		if b.err != nil {
+			return b
+		}
+
+		pth := strings.TrimSuffix(fld, ".*")
+
+		if fld == "*" {
+			b.fds = append(b.fds, fld)
+			continue
+		}
+
+		typ, ok := filter.FieldType(b.typ, pth)
+
+		if !ok {
+			b.err = &FieldError{Field: fld, Err: ErrUnknownField}
+			return b
+		}
+
+		if pth != fld && (typ.Kind() != reflect.Struct || typ == timeType) {
+			b.err = &FieldError{Field: fld, Err: ErrUnknownField, Msg: fmt.Sprintf("`%s` has no nested fields", pth)}
+			return b
+		}
+
+		b.fds = append(b.fds, fld)
+	}
+
+	return b
+}
+
+// Filter adds the filter, the value needs to match the type of the field
+// (for example `namespace.identifier` is int and `is_part_of.identifier` is string).
+func (b *Builder) Filter(fld string, val interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	typ, ok := filter.FieldType(b.typ, fld)
+
+	if !ok {
+		b.err = &FieldError{Field: fld, Err: ErrUnknownField}
+		return b
+	}
+
+	if err := validate(typ, val); err != nil {
+		b.err = &FieldError{Field: fld, Err: ErrInvalidValue, Msg: err.Error()}
+		return b
+	}
+
+	b.fts = append(b.fts, &filter.Filter{Field: fld, Value: val})
+	return b
+}
+
+// Since sets the time to start streaming from.
+func (b *Builder) Since(snc time.Time) *Builder {
+	b.snc = &snc
+	return b
+}
+
+// Limit limits the number of results (works only for the articles API).
+func (b *Builder) Limit(lmt int) *Builder {
+	b.lmt = lmt
+	return b
+}
+
+// Parts selects the parts of the stream to connect to, each part is 5 partitions.
+func (b *Builder) Parts(pts ...int) *Builder {
+	b.pts = append(b.pts, pts...)
+	return b
+}
+
+// Offset sets the offset to resume the partition from.
+func (b *Builder) Offset(ptn int, off int64) *Builder {
+	if b.ofs == nil {
+		b.ofs = map[int]int64{}
+	}
+
+	b.ofs[ptn] = off
+	return b
+}
+
+// SincePartition sets the time to resume the partition from.
+func (b *Builder) SincePartition(ptn int, snc time.Time) *Builder {
+	if b.spp == nil {
+		b.spp = map[int]time.Time{}
+	}
+
+	b.spp[ptn] = snc
+	return b
+}
+
+// Err returns the first validation error.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// Request returns the request for the API client.
+func (b *Builder) Request() (*api.Request, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	req := &api.Request{
+		Since:             b.snc,
+		Limit:             b.lmt,
+		Parts:             b.pts,
+		Offsets:           b.ofs,
+		SincePerPartition: b.spp,
+	}
+
+	if len(b.fds) > 0 {
+		req.Fields = append([]string{}, b.fds...)
+	}
+
+	for _, ftr := range b.fts {
+		req.Filters = append(req.Filters, &api.Filter{Field: ftr.Field, Value: ftr.Value})
+	}
+
+	return req, nil
+}
+
+// ArticlesRequest returns the request for the realtime client, works only for schema.Article.
+func (b *Builder) ArticlesRequest() (*realtime.ArticlesRequest, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if b.typ != reflect.TypeOf(schema.Article{}) {
+		return nil, ErrNotArticles
+	}
+
+	req := &realtime.ArticlesRequest{
+		Parts:             b.pts,
+		Offsets:           b.ofs,
+		SincePerPartition: b.spp,
+	}
+
+	if b.snc != nil {
+		req.Since = *b.snc
+	}
+
+	if len(b.fds) > 0 {
+		req.Fields = append([]string{}, b.fds...)
+	}
+
+	for _, ftr := range b.fts {
+		req.Filters = append(req.Filters, realtime.Filter{Field: ftr.Field, Value: ftr.Value})
+	}
+
+	return req, nil
+}
+
+// validate checks if the value can be compared with the field of the type.
+func validate(typ reflect.Type, val interface{}) error {
+	vvl := reflect.ValueOf(val)
+
+	for vvl.Kind() == reflect.Ptr {
+		if vvl.IsNil() {
+			return fmt.Errorf("expected %s, got nil", typ)
+		}
+
+		vvl = vvl.Elem()
+	}
+
+	err := fmt.Errorf("expected %s, got %T", typ, val)
+
+	if typ == timeType {
+		switch vvl.Kind() {
+		case reflect.Struct:
+			if vvl.Type() == timeType {
+				return nil
+			}
+		case reflect.String:
+			if _, err := time.Parse(time.RFC3339, vvl.String()); err != nil {
+				return fmt.Errorf("expected RFC3339 time, got %q", vvl.String())
+			}
+
+			return nil
+		}
+
+		return err
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		if vvl.Kind() == reflect.String {
+			return nil
+		}
+	case reflect.Bool:
+		if vvl.Kind() == reflect.Bool {
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch vvl.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return nil
+		case reflect.Float32, reflect.Float64:
+			// values decoded from JSON are float64
+			if vvl.Float() == math.Trunc(vvl.Float()) {
+				return nil
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		switch vvl.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return nil
+		}
+	case reflect.Struct, reflect.Map:
+		return fmt.Errorf("can't filter by an object, use one of the nested fields")
+	}
+
+	return err
+}
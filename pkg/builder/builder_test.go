@@ -0,0 +1,202 @@
+package builder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/builder"
+	"github.com/protsack-stephan/wme/pkg/realtime"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type builderTestSuite struct {
+	suite.Suite
+	ent interface{}
+	fds []string
+	fts []*api.Filter
+	snc time.Time
+	pts []int
+	ofs map[int]int64
+	req *api.Request
+	rrq *realtime.ArticlesRequest
+	err error
+	rer error
+}
+
+func (s *builderTestSuite) build() *builder.Builder {
+	bdr := builder.New(s.ent).Fields(s.fds...)
+
+	for _, ftr := range s.fts {
+		bdr.Filter(ftr.Field, ftr.Value)
+	}
+
+	if !s.snc.IsZero() {
+		bdr.Since(s.snc)
+	}
+
+	bdr.Parts(s.pts...)
+
+	for ptn, off := range s.ofs {
+		bdr.Offset(ptn, off)
+	}
+
+	return bdr
+}
+
+func (s *builderTestSuite) TestRequest() {
+	req, err := s.build().Request()
+
+	if s.err != nil {
+		s.Assert().ErrorIs(err, s.err)
+		s.Assert().Nil(req)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.req, req)
+}
+
+func (s *builderTestSuite) TestArticlesRequest() {
+	req, err := s.build().ArticlesRequest()
+
+	if s.err != nil || s.rer != nil {
+		if s.err != nil {
+			s.Assert().ErrorIs(err, s.err)
+		} else {
+			s.Assert().ErrorIs(err, s.rer)
+		}
+
+		s.Assert().Nil(req)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.rrq, req)
+}
+
+func TestBuilder(t *testing.T) {
+	snc := time.Date(2023, 2, 28, 10, 0, 0, 0, time.UTC)
+
+	for _, testCase := range []*builderTestSuite{
+		{
+			ent: schema.Article{},
+			fds: []string{"name", "event.*", "categories.name"},
+			fts: []*api.Filter{
+				{Field: "namespace.identifier", Value: 0},
+				{Field: "is_part_of.identifier", Value: "enwiki"},
+			},
+			snc: snc,
+			req: &api.Request{
+				Since:  &snc,
+				Fields: []string{"name", "event.*", "categories.name"},
+				Filters: []*api.Filter{
+					{Field: "namespace.identifier", Value: 0},
+					{Field: "is_part_of.identifier", Value: "enwiki"},
+				},
+			},
+			rrq: &realtime.ArticlesRequest{
+				Since:  snc,
+				Fields: []string{"name", "event.*", "categories.name"},
+				Filters: []realtime.Filter{
+					{Field: "namespace.identifier", Value: 0},
+					{Field: "is_part_of.identifier", Value: "enwiki"},
+				},
+			},
+		},
+		{
+			ent: &schema.Article{},
+			fts: []*api.Filter{
+				{Field: "namespace.identifier", Value: float64(14)},
+				{Field: "date_modified", Value: "2023-02-28T10:00:00Z"},
+				{Field: "version.tags", Value: "mobile edit"},
+			},
+			req: &api.Request{
+				Filters: []*api.Filter{
+					{Field: "namespace.identifier", Value: float64(14)},
+					{Field: "date_modified", Value: "2023-02-28T10:00:00Z"},
+					{Field: "version.tags", Value: "mobile edit"},
+				},
+			},
+			rrq: &realtime.ArticlesRequest{
+				Filters: []realtime.Filter{
+					{Field: "namespace.identifier", Value: float64(14)},
+					{Field: "date_modified", Value: "2023-02-28T10:00:00Z"},
+					{Field: "version.tags", Value: "mobile edit"},
+				},
+			},
+		},
+		{
+			ent: schema.Article{},
+			fds: []string{"name"},
+			pts: []int{0, 9},
+			ofs: map[int]int64{0: 100, 46: 42},
+			req: &api.Request{
+				Fields:  []string{"name"},
+				Parts:   []int{0, 9},
+				Offsets: map[int]int64{0: 100, 46: 42},
+			},
+			rrq: &realtime.ArticlesRequest{
+				Fields:  []string{"name"},
+				Parts:   []int{0, 9},
+				Offsets: map[int]int64{0: 100, 46: 42},
+			},
+		},
+		{
+			ent: schema.Snapshot{},
+			fds: []string{"identifier", "is_part_of.*", "size.value"},
+			req: &api.Request{
+				Fields: []string{"identifier", "is_part_of.*", "size.value"},
+			},
+			rer: builder.ErrNotArticles,
+		},
+		{
+			ent: schema.Article{},
+			fts: []*api.Filter{
+				{Field: "in_language.identifer", Value: "en"},
+			},
+			err: builder.ErrUnknownField,
+		},
+		{
+			ent: schema.Batch{},
+			fds: []string{"identifier", "unknown"},
+			err: builder.ErrUnknownField,
+		},
+		{
+			ent: schema.Article{},
+			fds: []string{"name.*"},
+			err: builder.ErrUnknownField,
+		},
+		{
+			ent: schema.Article{},
+			fts: []*api.Filter{
+				{Field: "namespace.identifier", Value: "0"},
+			},
+			err: builder.ErrInvalidValue,
+		},
+		{
+			ent: schema.Thing{},
+			fts: []*api.Filter{
+				{Field: "is_part_of.identifier", Value: 1},
+			},
+			err: builder.ErrInvalidValue,
+		},
+		{
+			ent: schema.Article{},
+			fts: []*api.Filter{
+				{Field: "event", Value: "update"},
+			},
+			err: builder.ErrInvalidValue,
+		},
+		{
+			ent: schema.Article{},
+			fts: []*api.Filter{
+				{Field: "date_modified", Value: "yesterday"},
+			},
+			err: builder.ErrInvalidValue,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
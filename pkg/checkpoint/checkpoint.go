@@ -0,0 +1,201 @@
+// Package checkpoint persists the offsets of the handled articles per partition,
+// so the stream consumers can resume after a restart instead of replaying from scratch or from a coarse timestamp.
+package checkpoint
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/realtime"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// Checkpointer is an interface for persisting the offsets per partition, the key identifies the consumer.
+// Save stores the offsets of the provided partitions, other partitions are kept as they are.
+type Checkpointer interface {
+	Load(ctx context.Context, key string) (map[int]int64, error)
+	Save(ctx context.Context, key string, ofs map[int]int64) error
+}
+
+// NewCommitter creates a committer for the consumer with the key, offsets are flushed every 5 seconds by default.
+func NewCommitter(cpr Checkpointer, key string, ops ...func(cmr *Committer)) *Committer {
+	cmr := &Committer{
+		FlushInterval: time.Second * 5,
+		FlushTimeout:  time.Second * 10,
+		cpr:           cpr,
+		key:           key,
+		ofs:           map[int]int64{},
+	}
+
+	for _, opt := range ops {
+		opt(cmr)
+	}
+
+	return cmr
+}
+
+// Committer records the offsets of the articles once they are handled and periodically flushes them to the checkpointer.
+// Offsets are committed only after the callback succeeds, so after a restart every article is delivered at least once.
+type Committer struct {
+	FlushInterval time.Duration // How often the committed offsets are saved by Run.
+	FlushTimeout  time.Duration // Limits the last flush of Run, that happens after its context is cancelled.
+	cpr           Checkpointer
+	key           string
+	mut           sync.Mutex
+	fmu           sync.Mutex // keeps the saves in order, so the older offsets don't overwrite the newer ones
+	ofs           map[int]int64
+	dty           bool
+}
+
+// Commit records the offset of the article, articles without partition or offset are ignored.
+func (c *Committer) Commit(art *schema.Article) {
+	if art == nil || art.Event == nil || art.Event.Partition == nil || art.Event.Offset == nil {
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if off, ok := c.ofs[*art.Event.Partition]; !ok || *art.Event.Offset > off {
+		c.ofs[*art.Event.Partition] = *art.Event.Offset
+		c.dty = true
+	}
+}
+
+// Callback wraps the callback, the article is committed only if the callback succeeds.
+func (c *Committer) Callback(cbk api.ReadCallback) api.ReadCallback {
+	return func(art *schema.Article) error {
+		if err := cbk(art); err != nil {
+			return err
+		}
+
+		c.Commit(art)
+		return nil
+	}
+}
+
+// Flush saves the committed offsets if anything changed since the last flush.
+func (c *Committer) Flush(ctx context.Context) error {
+	c.fmu.Lock()
+	defer c.fmu.Unlock()
+
+	c.mut.Lock()
+
+	if !c.dty {
+		c.mut.Unlock()
+		return nil
+	}
+
+	ofs := map[int]int64{}
+
+	for ptn, off := range c.ofs {
+		ofs[ptn] = off
+	}
+
+	c.dty = false
+	c.mut.Unlock()
+
+	// saving can be slow, so the articles can be committed in the meantime
+	if err := c.cpr.Save(ctx, c.key, ofs); err != nil {
+		c.mut.Lock()
+		c.dty = true
+		c.mut.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Run flushes the offsets every FlushInterval in a blocking call, until the context is cancelled or the flush fails.
+// Offsets are flushed one last time (within FlushTimeout) before it returns.
+func (c *Committer) Run(ctx context.Context) error {
+	tkr := time.NewTicker(c.FlushInterval)
+	defer tkr.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// context is already cancelled, but the offsets still need to be saved
+			fcx, cancel := context.WithTimeout(context.Background(), c.FlushTimeout)
+			defer cancel()
+
+			if err := c.Flush(fcx); err != nil {
+				return err
+			}
+
+			return ctx.Err()
+		case <-tkr.C:
+			if err := c.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Offsets returns the saved offsets merged with the committed ones.
+func (c *Committer) Offsets(ctx context.Context) (map[int]int64, error) {
+	ofs, err := c.cpr.Load(ctx, c.key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ofs == nil {
+		ofs = map[int]int64{}
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for ptn, off := range c.ofs {
+		if sof, ok := ofs[ptn]; !ok || off > sof {
+			ofs[ptn] = off
+		}
+	}
+
+	return ofs, nil
+}
+
+// Request sets the offsets of the request, checkpoint takes precedence over the offsets that are already there.
+func (c *Committer) Request(ctx context.Context, req *api.Request) error {
+	ofs, err := c.Offsets(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	req.Offsets = merge(req.Offsets, ofs)
+	return nil
+}
+
+// ArticlesRequest sets the offsets of the realtime request, checkpoint takes precedence over the offsets that are already there.
+func (c *Committer) ArticlesRequest(ctx context.Context, req *realtime.ArticlesRequest) error {
+	ofs, err := c.Offsets(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	req.Offsets = merge(req.Offsets, ofs)
+	return nil
+}
+
+func merge(dst map[int]int64, src map[int]int64) map[int]int64 {
+	if len(src) == 0 {
+		return dst
+	}
+
+	out := map[int]int64{}
+
+	for ptn, off := range dst {
+		out[ptn] = off
+	}
+
+	for ptn, off := range src {
+		out[ptn] = off
+	}
+
+	return out
+}
@@ -0,0 +1,199 @@
+package checkpoint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/checkpoint"
+	"github.com/protsack-stephan/wme/pkg/realtime"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type failingCheckpointer struct {
+	checkpoint.Checkpointer
+	err error
+}
+
+func (f *failingCheckpointer) Save(ctx context.Context, key string, ofs map[int]int64) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	return f.Checkpointer.Save(ctx, key, ofs)
+}
+
+// blockingCheckpointer never finishes saving until the context is done.
+type blockingCheckpointer struct {
+	checkpoint.Checkpointer
+}
+
+func (b *blockingCheckpointer) Save(ctx context.Context, _ string, _ map[int]int64) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newArticle(ptn int, off int64) *schema.Article {
+	return &schema.Article{
+		Name: "Earth",
+		Event: &schema.Event{
+			Partition: &ptn,
+			Offset:    &off,
+		},
+	}
+}
+
+type committerTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	ats []*schema.Article
+	cbe map[int64]error
+	sof map[int]int64
+	rof map[int]int64
+	ofs map[int]int64
+	sve error
+}
+
+func (s *committerTestSuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+func (s *committerTestSuite) newCommitter() (*checkpoint.Committer, checkpoint.Checkpointer) {
+	mem := checkpoint.NewMemory()
+	s.Require().NoError(mem.Save(s.ctx, "enwiki", s.sof))
+
+	cpr := &failingCheckpointer{Checkpointer: mem, err: s.sve}
+	return checkpoint.NewCommitter(cpr, "enwiki"), mem
+}
+
+func (s *committerTestSuite) handle(cmr *checkpoint.Committer) {
+	cbk := cmr.Callback(func(art *schema.Article) error {
+		if art.Event == nil {
+			return nil
+		}
+
+		return s.cbe[*art.Event.Offset]
+	})
+
+	for _, art := range s.ats {
+		_ = cbk(art)
+	}
+}
+
+func (s *committerTestSuite) TestCallback() {
+	cmr, mem := s.newCommitter()
+	s.handle(cmr)
+
+	ofs, err := cmr.Offsets(s.ctx)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.ofs, ofs)
+
+	err = cmr.Flush(s.ctx)
+	sof, _ := mem.Load(s.ctx, "enwiki")
+
+	if s.sve != nil {
+		s.Assert().ErrorIs(err, s.sve)
+		s.Assert().Equal(s.sof, sof)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.ofs, sof)
+}
+
+func (s *committerTestSuite) TestRequest() {
+	cmr, _ := s.newCommitter()
+	s.handle(cmr)
+
+	req := &api.Request{Offsets: s.rof}
+	s.Assert().NoError(cmr.Request(s.ctx, req))
+
+	arq := &realtime.ArticlesRequest{Offsets: s.rof}
+	s.Assert().NoError(cmr.ArticlesRequest(s.ctx, arq))
+
+	for ptn, off := range s.rof {
+		if _, ok := s.ofs[ptn]; !ok {
+			s.Assert().Equal(off, req.Offsets[ptn])
+			s.Assert().Equal(off, arq.Offsets[ptn])
+		}
+	}
+
+	for ptn, off := range s.ofs {
+		s.Assert().Equal(off, req.Offsets[ptn])
+		s.Assert().Equal(off, arq.Offsets[ptn])
+	}
+}
+
+func (s *committerTestSuite) TestRun() {
+	if s.sve != nil {
+		s.T().Skip("flush failures are covered by the callback test")
+	}
+
+	cmr, mem := s.newCommitter()
+	cmr.FlushInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(s.ctx)
+	ers := make(chan error, 1)
+
+	go func() {
+		ers <- cmr.Run(ctx)
+	}()
+
+	s.handle(cmr)
+
+	s.Assert().Eventually(func() bool {
+		sof, _ := mem.Load(s.ctx, "enwiki")
+		ofs, _ := cmr.Offsets(s.ctx)
+		return len(sof) == len(ofs)
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	s.Assert().ErrorIs(<-ers, context.Canceled)
+
+	sof, _ := mem.Load(s.ctx, "enwiki")
+	ofs, _ := cmr.Offsets(s.ctx)
+	s.Assert().Equal(ofs, sof)
+}
+
+func (s *committerTestSuite) TestRunTimeout() {
+	if len(s.ats) == 0 || s.sve != nil {
+		s.T().Skip("only for successful flushes")
+	}
+
+	cmr := checkpoint.NewCommitter(&blockingCheckpointer{checkpoint.NewMemory()}, "enwiki", func(cmr *checkpoint.Committer) {
+		cmr.FlushInterval = time.Hour
+		cmr.FlushTimeout = time.Millisecond * 10
+	})
+	s.handle(cmr)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	s.Assert().ErrorIs(cmr.Run(ctx), context.DeadlineExceeded)
+}
+
+func TestCommitter(t *testing.T) {
+	for _, testCase := range []*committerTestSuite{
+		{
+			ats: []*schema.Article{newArticle(0, 10), newArticle(1, 20), newArticle(0, 11)},
+			ofs: map[int]int64{0: 11, 1: 20},
+		},
+		{
+			ats: []*schema.Article{newArticle(0, 10), newArticle(1, 20), newArticle(0, 11)},
+			cbe: map[int64]error{11: errors.New("failed to handle")},
+			sof: map[int]int64{2: 5},
+			rof: map[int]int64{3: 100, 0: 1},
+			ofs: map[int]int64{0: 10, 1: 20, 2: 5},
+		},
+		{
+			ats: []*schema.Article{newArticle(0, 10), {Name: "Mars"}},
+			sof: map[int]int64{0: 7},
+			ofs: map[int]int64{0: 10},
+			sve: errors.New("disk is full"),
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,98 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/protsack-stephan/wme/internal/atomicfile"
+)
+
+// NewFile creates new file based checkpointer, by default the file is readable only by the owner.
+func NewFile(pth string) *File {
+	return &File{
+		Path: pth,
+		Perm: 0600,
+	}
+}
+
+// File is a checkpointer that keeps the offsets of all the keys in a single JSON file.
+// The file is replaced atomically, so a crash during the save doesn't corrupt it.
+type File struct {
+	Path string
+	Perm os.FileMode
+	mut  sync.Mutex
+}
+
+// Load returns the offsets for the key, empty if there's nothing saved yet.
+func (f *File) Load(_ context.Context, key string) (map[int]int64, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	kys, err := f.read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if kys[key] == nil {
+		return map[int]int64{}, nil
+	}
+
+	return kys[key], nil
+}
+
+// Save stores the offsets for the key, offsets that are behind the saved ones are ignored.
+func (f *File) Save(_ context.Context, key string, ofs map[int]int64) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	kys, err := f.read()
+
+	if err != nil {
+		return err
+	}
+
+	if kys[key] == nil {
+		kys[key] = map[int]int64{}
+	}
+
+	for ptn, off := range ofs {
+		if sof, ok := kys[key][ptn]; !ok || off > sof {
+			kys[key][ptn] = off
+		}
+	}
+
+	return f.write(kys)
+}
+
+func (f *File) read() (map[string]map[int]int64, error) {
+	kys := map[string]map[int]int64{}
+	dta, err := os.ReadFile(f.Path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return kys, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dta) == 0 {
+		return kys, nil
+	}
+
+	return kys, json.Unmarshal(dta, &kys)
+}
+
+func (f *File) write(kys map[string]map[int]int64) error {
+	dta, err := json.Marshal(kys)
+
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(f.Path, dta, f.Perm)
+}
@@ -0,0 +1,51 @@
+package checkpoint
+
+import (
+	"context"
+	"sync"
+)
+
+// NewMemory creates an empty in-memory checkpointer.
+func NewMemory() *Memory {
+	return &Memory{
+		ofs: map[string]map[int]int64{},
+	}
+}
+
+// Memory is a checkpointer that keeps the offsets in memory, useful for tests and short lived consumers.
+type Memory struct {
+	mut sync.Mutex
+	ofs map[string]map[int]int64
+}
+
+// Load returns a copy of the offsets for the key.
+func (m *Memory) Load(_ context.Context, key string) (map[int]int64, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	ofs := map[int]int64{}
+
+	for ptn, off := range m.ofs[key] {
+		ofs[ptn] = off
+	}
+
+	return ofs, nil
+}
+
+// Save stores the offsets for the key, offsets that are behind the saved ones are ignored.
+func (m *Memory) Save(_ context.Context, key string, ofs map[int]int64) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.ofs[key] == nil {
+		m.ofs[key] = map[int]int64{}
+	}
+
+	for ptn, off := range ofs {
+		if sof, ok := m.ofs[key][ptn]; !ok || off > sof {
+			m.ofs[key][ptn] = off
+		}
+	}
+
+	return nil
+}
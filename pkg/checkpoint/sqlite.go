@@ -0,0 +1,89 @@
+package checkpoint
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Offset is a database row with the offset of a single partition.
+type Offset struct {
+	Key       string `gorm:"primarykey"`
+	Partition int    `gorm:"primarykey;autoIncrement:false"`
+	Offset    int64
+	UpdatedAt time.Time
+}
+
+// NewSQLite opens (or creates) the SQLite database file and prepares it for storing the offsets.
+func NewSQLite(pth string) (*DB, error) {
+	gdb, err := gorm.Open(sqlite.Open(pth), &gorm.Config{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDB(gdb)
+}
+
+// NewDB creates a checkpointer on top of an existing gorm connection, the offsets table is migrated automatically.
+func NewDB(gdb *gorm.DB) (*DB, error) {
+	if err := gdb.AutoMigrate(&Offset{}); err != nil {
+		return nil, err
+	}
+
+	return &DB{gdb: gdb}, nil
+}
+
+// DB is a checkpointer that keeps the offsets in a database table, one row per key and partition.
+type DB struct {
+	gdb *gorm.DB
+}
+
+// Load returns the offsets for the key.
+func (d *DB) Load(ctx context.Context, key string) (map[int]int64, error) {
+	rws := []*Offset{}
+
+	if err := d.gdb.WithContext(ctx).Where(&Offset{Key: key}).Find(&rws).Error; err != nil {
+		return nil, err
+	}
+
+	ofs := map[int]int64{}
+
+	for _, row := range rws {
+		ofs[row.Partition] = row.Offset
+	}
+
+	return ofs, nil
+}
+
+// Save upserts the offsets for the key in a single transaction, offsets that are behind the saved ones are ignored.
+func (d *DB) Save(ctx context.Context, key string, ofs map[int]int64) error {
+	if len(ofs) == 0 {
+		return nil
+	}
+
+	rws := []*Offset{}
+
+	for ptn, off := range ofs {
+		rws = append(rws, &Offset{
+			Key:       key,
+			Partition: ptn,
+			Offset:    off,
+		})
+	}
+
+	// the row is updated only if the offset moves forward
+	return d.gdb.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}, {Name: "partition"}},
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Gt{
+				Column: clause.Column{Table: "excluded", Name: "offset"},
+				Value:  clause.Column{Table: clause.CurrentTable, Name: "offset"},
+			},
+		}},
+		DoUpdates: clause.AssignmentColumns([]string{"offset", "updated_at"}),
+	}).Create(&rws).Error
+}
@@ -0,0 +1,97 @@
+package checkpoint_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protsack-stephan/wme/pkg/checkpoint"
+	"github.com/stretchr/testify/suite"
+)
+
+type checkpointerTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	dir string
+	new func(dir string) (checkpoint.Checkpointer, error)
+	cpr checkpoint.Checkpointer
+}
+
+func (s *checkpointerTestSuite) SetupTest() {
+	var err error
+	s.ctx = context.Background()
+	s.dir, err = os.MkdirTemp("", "checkpoint")
+	s.Require().NoError(err)
+
+	s.cpr, err = s.new(s.dir)
+	s.Require().NoError(err)
+}
+
+func (s *checkpointerTestSuite) TearDownTest() {
+	_ = os.RemoveAll(s.dir)
+}
+
+func (s *checkpointerTestSuite) TestLoadSave() {
+	ofs, err := s.cpr.Load(s.ctx, "enwiki")
+	s.Assert().NoError(err)
+	s.Assert().Empty(ofs)
+
+	s.Assert().NoError(s.cpr.Save(s.ctx, "enwiki", map[int]int64{0: 10, 1: 20}))
+	s.Assert().NoError(s.cpr.Save(s.ctx, "enwiki", map[int]int64{1: 25, 2: 5}))
+	s.Assert().NoError(s.cpr.Save(s.ctx, "frwiki", map[int]int64{0: 100}))
+
+	// concurrent committers can save the older offsets later, those don't move the checkpoint back
+	s.Assert().NoError(s.cpr.Save(s.ctx, "enwiki", map[int]int64{0: 7, 1: 25, 2: 6}))
+
+	ofs, err = s.cpr.Load(s.ctx, "enwiki")
+	s.Assert().NoError(err)
+	s.Assert().Equal(map[int]int64{0: 10, 1: 25, 2: 6}, ofs)
+
+	ofs, err = s.cpr.Load(s.ctx, "frwiki")
+	s.Assert().NoError(err)
+	s.Assert().Equal(map[int]int64{0: 100}, ofs)
+}
+
+func (s *checkpointerTestSuite) TestReopen() {
+	if _, ok := s.cpr.(*checkpoint.Memory); ok {
+		s.T().Skip("memory checkpointer doesn't persist the offsets")
+	}
+
+	s.Assert().NoError(s.cpr.Save(s.ctx, "enwiki", map[int]int64{7: 42}))
+
+	cpr, err := s.new(s.dir)
+	s.Assert().NoError(err)
+
+	ofs, err := cpr.Load(s.ctx, "enwiki")
+	s.Assert().NoError(err)
+	s.Assert().Equal(map[int]int64{7: 42}, ofs)
+
+	if fle, ok := s.cpr.(*checkpoint.File); ok {
+		fst, err := os.Stat(fle.Path)
+		s.Assert().NoError(err)
+		s.Assert().Equal(os.FileMode(0600), fst.Mode().Perm())
+	}
+}
+
+func TestCheckpointer(t *testing.T) {
+	for _, testCase := range []*checkpointerTestSuite{
+		{
+			new: func(_ string) (checkpoint.Checkpointer, error) {
+				return checkpoint.NewMemory(), nil
+			},
+		},
+		{
+			new: func(dir string) (checkpoint.Checkpointer, error) {
+				return checkpoint.NewFile(filepath.Join(dir, "offsets", "checkpoint.json")), nil
+			},
+		},
+		{
+			new: func(dir string) (checkpoint.Checkpointer, error) {
+				return checkpoint.NewSQLite(filepath.Join(dir, "checkpoint.db"))
+			},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,64 @@
+package consumer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Coordinator keeps track of the live members of the group, members that stop sending heartbeats expire after the TTL.
+type Coordinator interface {
+	Heartbeat(ctx context.Context, mbr string, ttl time.Duration) error
+	Leave(ctx context.Context, mbr string) error
+	Members(ctx context.Context) ([]string, error)
+}
+
+// NewMemory creates a coordinator for the workers running in the same process.
+func NewMemory() *Memory {
+	return &Memory{
+		mbs: map[string]time.Time{},
+	}
+}
+
+// Memory is an in-memory coordinator, works only for the workers of a single process.
+type Memory struct {
+	mut sync.Mutex
+	mbs map[string]time.Time
+}
+
+// Heartbeat registers the member or extends its membership.
+func (m *Memory) Heartbeat(_ context.Context, mbr string, ttl time.Duration) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.mbs[mbr] = time.Now().Add(ttl)
+	return nil
+}
+
+// Leave removes the member from the group.
+func (m *Memory) Leave(_ context.Context, mbr string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	delete(m.mbs, mbr)
+	return nil
+}
+
+// Members returns the sorted list of the live members.
+func (m *Memory) Members(_ context.Context) ([]string, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	mbs := []string{}
+	now := time.Now()
+
+	for mbr, exp := range m.mbs {
+		if exp.After(now) {
+			mbs = append(mbs, mbr)
+		}
+	}
+
+	sort.Strings(mbs)
+	return mbs, nil
+}
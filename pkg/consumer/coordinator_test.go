@@ -0,0 +1,83 @@
+package consumer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/consumer"
+	"github.com/stretchr/testify/suite"
+)
+
+type coordinatorTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	dir string
+	new func(dir string, grp string) (consumer.Coordinator, error)
+	crd consumer.Coordinator
+}
+
+func (s *coordinatorTestSuite) SetupTest() {
+	var err error
+	s.ctx = context.Background()
+	s.dir, err = os.MkdirTemp("", "consumer")
+	s.Require().NoError(err)
+
+	s.crd, err = s.new(s.dir, "enwiki")
+	s.Require().NoError(err)
+}
+
+func (s *coordinatorTestSuite) TearDownTest() {
+	_ = os.RemoveAll(s.dir)
+}
+
+func (s *coordinatorTestSuite) TestMembers() {
+	mbs, err := s.crd.Members(s.ctx)
+	s.Assert().NoError(err)
+	s.Assert().Empty(mbs)
+
+	s.Assert().NoError(s.crd.Heartbeat(s.ctx, "worker-b", time.Minute))
+	s.Assert().NoError(s.crd.Heartbeat(s.ctx, "worker-a", time.Minute))
+	s.Assert().NoError(s.crd.Heartbeat(s.ctx, "worker-c", time.Minute))
+	s.Assert().NoError(s.crd.Heartbeat(s.ctx, "worker-b", time.Minute))
+
+	mbs, err = s.crd.Members(s.ctx)
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{"worker-a", "worker-b", "worker-c"}, mbs)
+
+	s.Assert().NoError(s.crd.Leave(s.ctx, "worker-b"))
+
+	mbs, err = s.crd.Members(s.ctx)
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{"worker-a", "worker-c"}, mbs)
+}
+
+func (s *coordinatorTestSuite) TestExpiry() {
+	s.Assert().NoError(s.crd.Heartbeat(s.ctx, "worker-a", time.Minute))
+	s.Assert().NoError(s.crd.Heartbeat(s.ctx, "worker-b", time.Millisecond*10))
+
+	time.Sleep(time.Millisecond * 20)
+
+	mbs, err := s.crd.Members(s.ctx)
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{"worker-a"}, mbs)
+}
+
+func TestCoordinator(t *testing.T) {
+	for _, testCase := range []*coordinatorTestSuite{
+		{
+			new: func(_ string, _ string) (consumer.Coordinator, error) {
+				return consumer.NewMemory(), nil
+			},
+		},
+		{
+			new: func(dir string, grp string) (consumer.Coordinator, error) {
+				return consumer.NewSQLite(filepath.Join(dir, "group.db"), grp)
+			},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
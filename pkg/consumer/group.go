@@ -0,0 +1,400 @@
+// Package consumer splits the articles stream between the workers of a consumer group.
+// Workers can be goroutines (with the in-memory coordinator) or separate processes (with the SQLite coordinator),
+// the parts of the stream are reassigned every time a worker joins or leaves the group.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/checkpoint"
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// DefaultPartitions is the number of partitions in the articles stream.
+const DefaultPartitions = 50
+
+// DefaultPartSize is the number of partitions in a single part.
+const DefaultPartSize = 5
+
+// PartitionStats describes the progress of a single partition.
+type PartitionStats struct {
+	Partition     int           // Partition number.
+	Member        string        // Member that handled the last article.
+	Offset        int64         // Offset of the last handled article.
+	DatePublished time.Time     // Publishing date of the last handled article.
+	Lag           time.Duration // Time since the last handled article was published, stream doesn't expose the latest offsets.
+	Articles      int64         // Number of handled articles.
+}
+
+// NewGroup creates a consumer group for the stream request, parts of the request are replaced by the assignments.
+func NewGroup(ast api.ArticlesStreamer, crd Coordinator, req *api.Request, ops ...func(grp *Group)) *Group {
+	grp := &Group{
+		Partitions:        DefaultPartitions,
+		PartSize:          DefaultPartSize,
+		HeartbeatInterval: time.Second * 5,
+		CheckpointKey:     "default",
+		ast:               ast,
+		crd:               crd,
+		req:               req,
+		sts:               map[int]*PartitionStats{},
+	}
+
+	if grp.req == nil {
+		grp.req = new(api.Request)
+	}
+
+	for _, opt := range ops {
+		opt(grp)
+	}
+
+	return grp
+}
+
+// Group assigns continuous ranges of the parts to the live members and runs one managed stream per member.
+type Group struct {
+	Partitions        int                     // Total number of partitions in the stream.
+	PartSize          int                     // Number of partitions in a single part.
+	HeartbeatInterval time.Duration           // How often the members refresh their membership and check the assignments, members expire after 3 intervals.
+	Checkpointer      checkpoint.Checkpointer // Shared offsets, so the reassigned parts resume where the previous member stopped, optional.
+	CheckpointKey     string                  // Key of the group offsets in the checkpointer.
+	StreamOptions     []func(stm *api.Stream) // Options for the managed streams, for example the retry policy.
+	ast               api.ArticlesStreamer
+	crd               Coordinator
+	req               *api.Request
+	mut               sync.Mutex
+	sts               map[int]*PartitionStats
+}
+
+// Parts returns the number of parts in the stream.
+func (g *Group) Parts() int {
+	return (g.Partitions + g.PartSize - 1) / g.PartSize
+}
+
+// Run starts the workers in goroutines in a blocking call, the group is rebalanced if any of them exits.
+// Returns once all of the workers are done, with the first error that is not caused by the context.
+func (g *Group) Run(ctx context.Context, wks int, cbk api.ReadCallback) error {
+	hst, _ := os.Hostname()
+	wgp := new(sync.WaitGroup)
+	ers := make(chan error, wks)
+
+	for i := 0; i < wks; i++ {
+		wgp.Add(1)
+
+		go func(wkr *Worker) {
+			defer wgp.Done()
+			ers <- wkr.Run(ctx, cbk)
+		}(g.Worker(fmt.Sprintf("%s-%d-%d", hst, os.Getpid(), i)))
+	}
+
+	wgp.Wait()
+	close(ers)
+
+	err := ctx.Err()
+
+	for wer := range ers {
+		if wer != nil && !errors.Is(wer, context.Canceled) && !errors.Is(wer, context.DeadlineExceeded) {
+			return wer
+		}
+	}
+
+	return err
+}
+
+// Worker creates a member of the group, the name has to be unique within the group.
+func (g *Group) Worker(mbr string) *Worker {
+	return &Worker{
+		grp: g,
+		mbr: mbr,
+	}
+}
+
+// Stats returns the progress of the partitions sorted by the partition number.
+func (g *Group) Stats() []*PartitionStats {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	sts := []*PartitionStats{}
+
+	for _, pst := range g.sts {
+		cpy := *pst
+
+		if !cpy.DatePublished.IsZero() {
+			cpy.Lag = time.Since(cpy.DatePublished)
+		}
+
+		sts = append(sts, &cpy)
+	}
+
+	sort.Slice(sts, func(i, j int) bool {
+		return sts[i].Partition < sts[j].Partition
+	})
+
+	return sts
+}
+
+func (g *Group) track(mbr string, art *schema.Article) {
+	if art == nil || art.Event == nil || art.Event.Partition == nil {
+		return
+	}
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	pst, ok := g.sts[*art.Event.Partition]
+
+	if !ok {
+		pst = &PartitionStats{Partition: *art.Event.Partition}
+		g.sts[pst.Partition] = pst
+	}
+
+	pst.Member = mbr
+	pst.Articles++
+
+	if art.Event.Offset != nil {
+		pst.Offset = *art.Event.Offset
+	}
+
+	if art.Event.DatePublished != nil {
+		pst.DatePublished = *art.Event.DatePublished
+	}
+}
+
+// assign returns the continuous range of the parts for the member, empty if there are more members than parts.
+func assign(pts int, mbs []string, mbr string) []int {
+	idx := sort.SearchStrings(mbs, mbr)
+
+	if idx >= len(mbs) || mbs[idx] != mbr {
+		return []int{}
+	}
+
+	asg := []int{}
+
+	for prt := idx * pts / len(mbs); prt < (idx+1)*pts/len(mbs); prt++ {
+		asg = append(asg, prt)
+	}
+
+	return asg
+}
+
+// subscription is a managed stream for the current assignment.
+type subscription struct {
+	cnl context.CancelFunc
+	dne chan error
+}
+
+// Worker is a single member of the group.
+type Worker struct {
+	grp *Group
+	mbr string
+	cmr *checkpoint.Committer
+	sub *subscription
+	mut sync.Mutex
+	pts []int
+}
+
+// Parts returns the current assignment of the worker.
+func (w *Worker) Parts() []int {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	return append([]int{}, w.pts...)
+}
+
+// Run joins the group and streams the assigned parts in a blocking call, until the context is cancelled or the stream fails.
+// The worker leaves the group on exit, so the rest of the members take over its parts.
+func (w *Worker) Run(ctx context.Context, cbk api.ReadCallback) error {
+	tkr := time.NewTicker(w.grp.HeartbeatInterval)
+	defer tkr.Stop()
+
+	if w.grp.Checkpointer != nil {
+		w.cmr = checkpoint.NewCommitter(w.grp.Checkpointer, w.grp.CheckpointKey)
+	}
+
+	defer func() {
+		_ = w.grp.crd.Leave(context.Background(), w.mbr)
+	}()
+
+	for {
+		if err := w.rebalance(ctx, cbk); err != nil {
+			_ = w.stop()
+			return err
+		}
+
+		var dne chan error
+
+		if w.sub != nil {
+			dne = w.sub.dne
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := w.stop(); err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+
+			return ctx.Err()
+		case err := <-dne:
+			// the stream failed permanently, the error is already received
+			w.sub = nil
+
+			if ser := w.stop(); ser != nil && err == nil {
+				err = ser
+			}
+
+			return err
+		case <-tkr.C:
+			if w.cmr != nil {
+				if err := w.cmr.Flush(ctx); err != nil {
+					_ = w.stop()
+					return err
+				}
+			}
+		}
+	}
+}
+
+// rebalance refreshes the membership and restarts the stream if the assignment changed.
+func (w *Worker) rebalance(ctx context.Context, cbk api.ReadCallback) error {
+	if err := w.grp.crd.Heartbeat(ctx, w.mbr, w.grp.HeartbeatInterval*3); err != nil {
+		return err
+	}
+
+	mbs, err := w.grp.crd.Members(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	pts := assign(w.grp.Parts(), mbs, w.mbr)
+	ops := w.Parts()
+
+	if equal(pts, ops) {
+		return nil
+	}
+
+	if err := w.stop(); err != nil {
+		return err
+	}
+
+	w.mut.Lock()
+	w.pts = pts
+	w.mut.Unlock()
+
+	// the previous owner notices the change on its next heartbeat, stops and flushes the offsets,
+	// the gained parts are streamed one interval later to resume from those offsets in most cases,
+	// the handoff is best effort, a late flush means some of the articles are delivered again
+	dly := time.Duration(0)
+
+	if !contains(ops, pts) {
+		dly = w.grp.HeartbeatInterval
+	}
+
+	if len(pts) > 0 {
+		w.subscribe(ctx, pts, dly, cbk)
+	}
+
+	return nil
+}
+
+// stop waits for the current stream to finish and saves its offsets, so the next owner can pick them up.
+// Returns the stream error if it failed for any other reason than being stopped.
+func (w *Worker) stop() error {
+	if w.sub != nil {
+		w.sub.cnl()
+		err := <-w.sub.dne
+		w.sub = nil
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+
+	if w.cmr != nil {
+		return w.cmr.Flush(context.Background())
+	}
+
+	return nil
+}
+
+func (w *Worker) subscribe(ctx context.Context, pts []int, dly time.Duration, cbk api.ReadCallback) {
+	sctx, cancel := context.WithCancel(ctx)
+	w.sub = &subscription{
+		cnl: cancel,
+		dne: make(chan error, 1),
+	}
+
+	req := *w.grp.req
+	req.Parts = pts
+
+	go func(sub *subscription, cmr *checkpoint.Committer) {
+		defer cancel()
+
+		if dly > 0 {
+			tmr := time.NewTimer(dly)
+
+			select {
+			case <-sctx.Done():
+				tmr.Stop()
+				sub.dne <- sctx.Err()
+				return
+			case <-tmr.C:
+			}
+		}
+
+		if cmr != nil {
+			if err := cmr.Request(sctx, &req); err != nil {
+				sub.dne <- err
+				return
+			}
+		}
+
+		stm := api.NewStream(w.grp.ast, &req, w.grp.StreamOptions...)
+		sub.dne <- stm.Subscribe(sctx, func(art *schema.Article) error {
+			if err := cbk(art); err != nil {
+				return err
+			}
+
+			w.grp.track(w.mbr, art)
+
+			if cmr != nil {
+				cmr.Commit(art)
+			}
+
+			return nil
+		})
+	}(w.sub, w.cmr)
+}
+
+// contains checks if all of the parts of b are in a, both are sorted.
+func contains(a []int, b []int) bool {
+	for _, prt := range b {
+		idx := sort.SearchInts(a, prt)
+
+		if idx >= len(a) || a[idx] != prt {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equal(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
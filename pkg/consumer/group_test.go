@@ -0,0 +1,205 @@
+package consumer_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/checkpoint"
+	"github.com/protsack-stephan/wme/pkg/consumer"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+// groupStreamer sends one article for the first partition of every part and waits for the context.
+type groupStreamer struct {
+	mut sync.Mutex
+	rqs []*api.Request
+}
+
+func (g *groupStreamer) StreamArticles(ctx context.Context, req *api.Request, cbk api.ReadCallback) error {
+	g.mut.Lock()
+	g.rqs = append(g.rqs, req)
+	g.mut.Unlock()
+
+	for _, prt := range req.Parts {
+		ptn := prt * consumer.DefaultPartSize
+		off := req.Offsets[ptn] + 1
+		dpb := time.Now().Add(-time.Minute)
+
+		err := cbk(&schema.Article{
+			Name: "Earth",
+			Event: &schema.Event{
+				Partition:     &ptn,
+				Offset:        &off,
+				DatePublished: &dpb,
+			},
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (g *groupStreamer) requests() []*api.Request {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	return append([]*api.Request{}, g.rqs...)
+}
+
+type groupTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	gsr *groupStreamer
+	cpr checkpoint.Checkpointer
+	grp *consumer.Group
+}
+
+func (s *groupTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.gsr = new(groupStreamer)
+	s.cpr = checkpoint.NewMemory()
+	s.grp = consumer.NewGroup(s.gsr, consumer.NewMemory(), &api.Request{Fields: []string{"name"}}, func(grp *consumer.Group) {
+		grp.HeartbeatInterval = time.Millisecond * 10
+		grp.Checkpointer = s.cpr
+	})
+}
+
+func (s *groupTestSuite) TestRebalance() {
+	wka := s.grp.Worker("worker-a")
+	wkb := s.grp.Worker("worker-b")
+
+	cta, cla := context.WithCancel(s.ctx)
+	defer cla()
+
+	ctb, clb := context.WithCancel(s.ctx)
+	defer clb()
+
+	ers := make(chan error, 2)
+	cbk := func(art *schema.Article) error { return nil }
+
+	go func() { ers <- wka.Run(cta, cbk) }()
+	go func() { ers <- wkb.Run(ctb, cbk) }()
+
+	s.Assert().Eventually(func() bool {
+		return len(wka.Parts()) == 5 && len(wkb.Parts()) == 5
+	}, time.Second, time.Millisecond)
+	s.Assert().Equal([]int{0, 1, 2, 3, 4}, wka.Parts())
+	s.Assert().Equal([]int{5, 6, 7, 8, 9}, wkb.Parts())
+
+	clb()
+	s.Assert().ErrorIs(<-ers, context.Canceled)
+
+	s.Assert().Eventually(func() bool {
+		return len(wka.Parts()) == 10
+	}, time.Second, time.Millisecond)
+
+	// partitions of worker-b are resumed from its offsets
+	s.Assert().Eventually(func() bool {
+		rqs := s.gsr.requests()
+		lst := rqs[len(rqs)-1]
+		return len(lst.Parts) == 10 && lst.Offsets[25] > 0 && lst.Offsets[45] > 0
+	}, time.Second, time.Millisecond)
+
+	for _, req := range s.gsr.requests() {
+		s.Assert().Contains(req.Fields, "name")
+	}
+
+	sts := s.grp.Stats()
+	s.Assert().Len(sts, 10)
+
+	for i, pst := range sts {
+		s.Assert().Equal(i*consumer.DefaultPartSize, pst.Partition)
+		s.Assert().GreaterOrEqual(pst.Lag, time.Minute)
+		s.Assert().NotEmpty(pst.Member)
+	}
+
+	cla()
+	s.Assert().ErrorIs(<-ers, context.Canceled)
+}
+
+func (s *groupTestSuite) TestHandoff() {
+	s.grp.HeartbeatInterval = time.Millisecond * 50
+	wka := s.grp.Worker("worker-a")
+	wkb := s.grp.Worker("worker-b")
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	ers := make(chan error, 2)
+	cbk := func(art *schema.Article) error { return nil }
+
+	go func() { ers <- wka.Run(ctx, cbk) }()
+
+	s.Assert().Eventually(func() bool {
+		return len(s.grp.Stats()) == 10
+	}, time.Second, time.Millisecond)
+
+	go func() { ers <- wkb.Run(ctx, cbk) }()
+
+	// the new owner resumes from the offsets flushed by the previous one
+	s.Assert().Eventually(func() bool {
+		return len(wkb.Parts()) == 5 && len(s.gsr.requests()) == 3
+	}, time.Second, time.Millisecond)
+
+	for _, req := range s.gsr.requests() {
+		if len(req.Parts) == 0 || req.Parts[0] != 5 {
+			continue
+		}
+
+		for _, prt := range req.Parts {
+			s.Assert().Equal(int64(1), req.Offsets[prt*consumer.DefaultPartSize])
+		}
+	}
+
+	cancel()
+	s.Assert().ErrorIs(<-ers, context.Canceled)
+	s.Assert().ErrorIs(<-ers, context.Canceled)
+}
+
+func (s *groupTestSuite) TestRun() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	ers := make(chan error, 1)
+
+	go func() {
+		ers <- s.grp.Run(ctx, 3, func(art *schema.Article) error {
+			return nil
+		})
+	}()
+
+	// every part is handled, no matter how the parts were assigned while the workers were joining
+	s.Assert().Eventually(func() bool {
+		return len(s.grp.Stats()) == 10
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	s.Assert().ErrorIs(<-ers, context.Canceled)
+
+	for _, pst := range s.grp.Stats() {
+		s.Assert().GreaterOrEqual(pst.Offset, int64(1))
+	}
+}
+
+func (s *groupTestSuite) TestCallbackError() {
+	wkr := s.grp.Worker("worker-a")
+	err := wkr.Run(s.ctx, func(art *schema.Article) error {
+		return errors.New("failed to handle")
+	})
+
+	s.Assert().EqualError(err, "failed to handle")
+	s.Assert().Empty(s.grp.Stats())
+}
+
+func TestGroup(t *testing.T) {
+	suite.Run(t, new(groupTestSuite))
+}
@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/realtime"
+)
+
+// Realtime adapts the realtime client, so its Articles stream can be used by the group.
+func Realtime(clt *realtime.Client) api.ArticlesStreamer {
+	return &realtimeStreamer{clt: clt}
+}
+
+type realtimeStreamer struct {
+	clt *realtime.Client
+}
+
+func (r *realtimeStreamer) StreamArticles(ctx context.Context, req *api.Request, cbk api.ReadCallback) error {
+	arq := &realtime.ArticlesRequest{
+		Fields:            req.Fields,
+		Parts:             req.Parts,
+		Offsets:           req.Offsets,
+		SincePerPartition: req.SincePerPartition,
+	}
+
+	if req.Since != nil {
+		arq.Since = *req.Since
+	}
+
+	for _, ftr := range req.Filters {
+		if ftr != nil {
+			arq.Filters = append(arq.Filters, *ftr)
+		}
+	}
+
+	return r.clt.Articles(ctx, arq, cbk)
+}
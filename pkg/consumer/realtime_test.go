@@ -0,0 +1,92 @@
+package consumer_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/api"
+	"github.com/protsack-stephan/wme/pkg/consumer"
+	"github.com/protsack-stephan/wme/pkg/realtime"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type realtimeTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	ast api.ArticlesStreamer
+	req *api.Request
+	arq *realtime.ArticlesRequest
+	rrq *realtime.ArticlesRequest
+}
+
+func (s *realtimeTestSuite) SetupTest() {
+	s.rrq = nil
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.rrq = new(realtime.ArticlesRequest)
+		_ = json.NewDecoder(r.Body).Decode(s.rrq)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"name":"Earth"}` + "\n" + `{"name":"Mars"}`))
+	}))
+
+	rlt := realtime.NewClient()
+	rlt.BaseURL = fmt.Sprintf("%s/v2", s.srv.URL)
+	s.ast = consumer.Realtime(rlt)
+}
+
+func (s *realtimeTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *realtimeTestSuite) TestStreamArticles() {
+	nms := []string{}
+	err := s.ast.StreamArticles(context.Background(), s.req, func(art *schema.Article) error {
+		nms = append(nms, art.Name)
+		return nil
+	})
+
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{"Earth", "Mars"}, nms)
+	s.Assert().Equal(s.arq, s.rrq)
+}
+
+func TestRealtime(t *testing.T) {
+	snc := time.Date(2023, 2, 28, 10, 0, 0, 0, time.UTC)
+
+	for _, testCase := range []*realtimeTestSuite{
+		{
+			req: &api.Request{},
+			arq: &realtime.ArticlesRequest{},
+		},
+		{
+			req: &api.Request{
+				Since:  &snc,
+				Fields: []string{"name", "event.*"},
+				Filters: []*api.Filter{
+					{Field: "is_part_of.identifier", Value: "enwiki"},
+				},
+				Parts:             []int{0, 1},
+				Offsets:           map[int]int64{0: 10, 7: 42},
+				SincePerPartition: map[int]time.Time{3: snc.Add(time.Hour)},
+			},
+			arq: &realtime.ArticlesRequest{
+				Since:  snc,
+				Fields: []string{"name", "event.*"},
+				Filters: []realtime.Filter{
+					{Field: "is_part_of.identifier", Value: "enwiki"},
+				},
+				Parts:             []int{0, 1},
+				Offsets:           map[int]int64{0: 10, 7: 42},
+				SincePerPartition: map[int]time.Time{3: snc.Add(time.Hour)},
+			},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,82 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Member is a database row of a single group member.
+type Member struct {
+	Group     string `gorm:"primarykey"`
+	Member    string `gorm:"primarykey"`
+	ExpiresAt time.Time
+}
+
+// NewSQLite opens (or creates) the SQLite database file shared by the processes of the group.
+func NewSQLite(pth string, grp string) (*DB, error) {
+	gdb, err := gorm.Open(sqlite.Open(pth), &gorm.Config{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDB(gdb, grp)
+}
+
+// NewDB creates a coordinator on top of an existing gorm connection, the members table is migrated automatically.
+func NewDB(gdb *gorm.DB, grp string) (*DB, error) {
+	if err := gdb.AutoMigrate(&Member{}); err != nil {
+		return nil, err
+	}
+
+	return &DB{gdb: gdb, grp: grp}, nil
+}
+
+// DB is a coordinator that keeps the members in a database table, so the workers can run in separate processes.
+type DB struct {
+	gdb *gorm.DB
+	grp string
+}
+
+// Heartbeat registers the member or extends its membership.
+func (d *DB) Heartbeat(ctx context.Context, mbr string, ttl time.Duration) error {
+	return d.gdb.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group"}, {Name: "member"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(&Member{
+		Group:     d.grp,
+		Member:    mbr,
+		ExpiresAt: time.Now().Add(ttl),
+	}).Error
+}
+
+// Leave removes the member from the group.
+func (d *DB) Leave(ctx context.Context, mbr string) error {
+	return d.gdb.WithContext(ctx).Delete(&Member{Group: d.grp, Member: mbr}).Error
+}
+
+// Members returns the sorted list of the live members.
+func (d *DB) Members(ctx context.Context) ([]string, error) {
+	rws := []*Member{}
+	err := d.gdb.WithContext(ctx).
+		Where(&Member{Group: d.grp}).
+		Where("expires_at > ?", time.Now()).
+		Order("member").
+		Find(&rws).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	mbs := []string{}
+
+	for _, row := range rws {
+		mbs = append(mbs, row.Member)
+	}
+
+	return mbs, nil
+}
@@ -0,0 +1,73 @@
+package dispatch
+
+import (
+	"sync"
+
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// entry is a dispatched article that waits for the articles of the same partition dispatched before it.
+type entry struct {
+	art *schema.Article
+	dne bool
+}
+
+// tracker commits the articles in the dispatch order of each partition,
+// so the committed offset never skips over the articles that are still queued or being handled.
+type tracker struct {
+	mut sync.Mutex
+	pns map[int][]*entry
+	cmt func(art *schema.Article)
+}
+
+func newTracker(cmt func(art *schema.Article)) *tracker {
+	return &tracker{
+		pns: map[int][]*entry{},
+		cmt: cmt,
+	}
+}
+
+// add records the article in the order of its partition, articles without the partition are not tracked.
+func (t *tracker) add(art *schema.Article) *entry {
+	if t == nil || art == nil || art.Event == nil || art.Event.Partition == nil {
+		return nil
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	ent := &entry{art: art}
+	t.pns[*art.Event.Partition] = append(t.pns[*art.Event.Partition], ent)
+	return ent
+}
+
+// done marks the article as handled and commits the last article of the handled prefix of the partition.
+func (t *tracker) done(ent *entry) {
+	if t == nil || ent == nil {
+		return
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	ent.dne = true
+	ptn := *ent.art.Event.Partition
+	ens := t.pns[ptn]
+	var lst *schema.Article
+
+	for len(ens) > 0 && ens[0].dne {
+		lst = ens[0].art
+		ens[0] = nil
+		ens = ens[1:]
+	}
+
+	if len(ens) == 0 {
+		delete(t.pns, ptn)
+	} else {
+		t.pns[ptn] = ens
+	}
+
+	if lst != nil {
+		t.cmt(lst)
+	}
+}
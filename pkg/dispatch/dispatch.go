@@ -0,0 +1,179 @@
+// Package dispatch handles the stream articles concurrently while keeping the order of the events for the same article.
+// Articles are hashed by (is_part_of.identifier, identifier) onto a fixed number of ordered queues, each with its own worker.
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/protsack-stephan/wme/schema/v2"
+)
+
+// ErrClosed is returned when the article is dispatched after the dispatcher was closed.
+var ErrClosed = errors.New("dispatch: dispatcher is closed")
+
+// Handler handles a single article, has the same signature as the api and realtime callbacks.
+type Handler func(art *schema.Article) error
+
+// New creates a dispatcher and starts the workers, by default there are 10 workers with queues of 100 articles.
+// Workers stop once the context is cancelled or any of the handlers fails.
+func New(ctx context.Context, hdl Handler, ops ...func(dsp *Dispatcher)) *Dispatcher {
+	dsp := &Dispatcher{
+		Workers:   10,
+		QueueSize: 100,
+		hdl:       hdl,
+		dne:       make(chan struct{}),
+	}
+
+	for _, opt := range ops {
+		opt(dsp)
+	}
+
+	if dsp.Workers < 1 {
+		dsp.Workers = 1
+	}
+
+	if dsp.Commit != nil {
+		dsp.trk = newTracker(dsp.Commit)
+	}
+
+	dsp.ctx = ctx
+	dsp.qus = make([]chan *item, dsp.Workers)
+
+	for i := range dsp.qus {
+		dsp.qus[i] = make(chan *item, dsp.QueueSize)
+		dsp.wgp.Add(1)
+		go dsp.work(dsp.qus[i])
+	}
+
+	return dsp
+}
+
+// item is a queued article with its place in the partition order.
+type item struct {
+	art *schema.Article
+	ent *entry
+}
+
+// Dispatcher runs the handler for the different articles in parallel, while the events for the same article are handled in order.
+// Queues are bounded, so Dispatch blocks once the queue of the article is full, which slows down reading from the stream.
+type Dispatcher struct {
+	Workers   int                       // Number of the ordered queues, each queue has a single worker.
+	QueueSize int                       // Number of articles that can wait in a single queue.
+	Commit    func(art *schema.Article) // Called with the article once it and every article of its partition dispatched before it are handled, optional.
+	ctx       context.Context
+	hdl       Handler
+	trk       *tracker
+	qus       []chan *item
+	wgp       sync.WaitGroup
+	mut       sync.RWMutex
+	cld       bool
+	eon       sync.Once
+	err       error
+	dne       chan struct{}
+}
+
+// Dispatch puts the article into its queue, can be used as the callback for the streams:
+//
+//	err := clt.StreamArticles(ctx, req, dsp.Dispatch)
+//
+// Returns the first handler error, so the stream stops once any of the handlers fails.
+func (d *Dispatcher) Dispatch(art *schema.Article) error {
+	d.mut.RLock()
+	defer d.mut.RUnlock()
+
+	if d.cld {
+		return ErrClosed
+	}
+
+	select {
+	case <-d.dne:
+		return d.err
+	case <-d.ctx.Done():
+		return d.ctx.Err()
+	case d.qus[d.queue(art)] <- &item{art: art, ent: d.trk.add(art)}:
+		return nil
+	}
+}
+
+// Close waits for the queued articles to be handled and stops the workers,
+// returns the first handler error (or the context error if the workers were stopped by the context).
+func (d *Dispatcher) Close() error {
+	d.mut.Lock()
+
+	if !d.cld {
+		d.cld = true
+
+		for _, que := range d.qus {
+			close(que)
+		}
+	}
+
+	d.mut.Unlock()
+	d.wgp.Wait()
+
+	select {
+	case <-d.dne:
+		return d.err
+	default:
+		return nil
+	}
+}
+
+func (d *Dispatcher) work(que chan *item) {
+	defer d.wgp.Done()
+
+	for {
+		select {
+		case <-d.dne:
+			return
+		case <-d.ctx.Done():
+			d.fail(d.ctx.Err())
+			return
+		case itm, ok := <-que:
+			if !ok {
+				return
+			}
+
+			if err := d.hdl(itm.art); err != nil {
+				d.fail(err)
+				return
+			}
+
+			d.trk.done(itm.ent)
+		}
+	}
+}
+
+func (d *Dispatcher) fail(err error) {
+	d.eon.Do(func() {
+		d.err = err
+		close(d.dne)
+	})
+}
+
+// queue picks the queue by the project and the identifier, name is used for the articles without the identifier.
+func (d *Dispatcher) queue(art *schema.Article) int {
+	if art == nil {
+		return 0
+	}
+
+	hsh := fnv.New32a()
+
+	if art.IsPartOf != nil {
+		_, _ = hsh.Write([]byte(art.IsPartOf.Identifier))
+	}
+
+	_, _ = hsh.Write([]byte{'/'})
+
+	if art.Identifier != 0 {
+		_, _ = hsh.Write([]byte(strconv.Itoa(art.Identifier)))
+	} else {
+		_, _ = hsh.Write([]byte(art.Name))
+	}
+
+	return int(hsh.Sum32() % uint32(len(d.qus)))
+}
@@ -0,0 +1,221 @@
+package dispatch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/dispatch"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+func newArticle(prj string, idr int, ver int) *schema.Article {
+	return &schema.Article{
+		Name:       fmt.Sprintf("%s_%d", prj, idr),
+		Identifier: idr,
+		IsPartOf:   &schema.Project{Identifier: prj},
+		Version:    &schema.Version{Identifier: ver},
+	}
+}
+
+type dispatchTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	wks int
+	ats int
+	vrs int
+}
+
+func (s *dispatchTestSuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+func (s *dispatchTestSuite) TestOrder() {
+	mut := new(sync.Mutex)
+	vrs := map[string][]int{}
+	act := int32(0)
+	mxa := int32(0)
+
+	dsp := dispatch.New(s.ctx, func(art *schema.Article) error {
+		cur := atomic.AddInt32(&act, 1)
+		defer atomic.AddInt32(&act, -1)
+
+		for {
+			mxv := atomic.LoadInt32(&mxa)
+
+			if cur <= mxv || atomic.CompareAndSwapInt32(&mxa, mxv, cur) {
+				break
+			}
+		}
+
+		time.Sleep(time.Microsecond * time.Duration(art.Version.Identifier%3*100))
+
+		mut.Lock()
+		defer mut.Unlock()
+
+		vrs[art.Name] = append(vrs[art.Name], art.Version.Identifier)
+		return nil
+	}, func(dsp *dispatch.Dispatcher) {
+		dsp.Workers = s.wks
+		dsp.QueueSize = 2
+	})
+
+	for ver := 0; ver < s.vrs; ver++ {
+		for idr := 0; idr < s.ats; idr++ {
+			s.Assert().NoError(dsp.Dispatch(newArticle("enwiki", idr, ver)))
+			s.Assert().NoError(dsp.Dispatch(newArticle("frwiki", idr, ver)))
+		}
+	}
+
+	s.Assert().NoError(dsp.Close())
+	s.Assert().Len(vrs, s.ats*2)
+
+	for nme, vls := range vrs {
+		s.Assert().Len(vls, s.vrs, nme)
+
+		for i, ver := range vls {
+			s.Assert().Equal(i, ver, nme)
+		}
+	}
+
+	if s.wks > 1 {
+		s.Assert().Greater(mxa, int32(1))
+	} else {
+		s.Assert().Equal(int32(1), mxa)
+	}
+}
+
+func (s *dispatchTestSuite) TestError() {
+	dsp := dispatch.New(s.ctx, func(art *schema.Article) error {
+		if art.Version.Identifier == 1 {
+			return errors.New("failed to handle")
+		}
+
+		return nil
+	}, func(dsp *dispatch.Dispatcher) {
+		dsp.Workers = s.wks
+	})
+
+	var err error
+
+	for ver := 0; ver < 1000 && err == nil; ver++ {
+		err = dsp.Dispatch(newArticle("enwiki", 1, ver))
+	}
+
+	s.Assert().EqualError(err, "failed to handle")
+	s.Assert().EqualError(dsp.Close(), "failed to handle")
+	s.Assert().ErrorIs(dsp.Dispatch(newArticle("enwiki", 1, 0)), dispatch.ErrClosed)
+}
+
+func (s *dispatchTestSuite) TestBackpressure() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	blk := make(chan struct{})
+	dsp := dispatch.New(ctx, func(art *schema.Article) error {
+		<-blk
+		return nil
+	}, func(dsp *dispatch.Dispatcher) {
+		dsp.Workers = s.wks
+		dsp.QueueSize = 1
+	})
+
+	// one article is being handled and one is waiting in the queue
+	s.Assert().NoError(dsp.Dispatch(newArticle("enwiki", 1, 0)))
+	s.Assert().NoError(dsp.Dispatch(newArticle("enwiki", 1, 1)))
+
+	dne := make(chan error, 1)
+
+	go func() {
+		dne <- dsp.Dispatch(newArticle("enwiki", 1, 2))
+	}()
+
+	select {
+	case <-dne:
+		s.Fail("dispatch should block while the queue is full")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(blk)
+	s.Assert().NoError(<-dne)
+	s.Assert().NoError(dsp.Close())
+}
+
+func (s *dispatchTestSuite) TestCommit() {
+	mut := new(sync.Mutex)
+	hds := map[int]map[int64]bool{}
+	cms := map[int][]int64{}
+
+	dsp := dispatch.New(s.ctx, func(art *schema.Article) error {
+		// later articles of the partition are often handled first
+		time.Sleep(time.Microsecond * time.Duration(art.Identifier%7*50))
+
+		mut.Lock()
+		defer mut.Unlock()
+
+		// articles without the partition are not committed
+		if art.Event == nil {
+			return nil
+		}
+
+		if hds[*art.Event.Partition] == nil {
+			hds[*art.Event.Partition] = map[int64]bool{}
+		}
+
+		hds[*art.Event.Partition][*art.Event.Offset] = true
+		return nil
+	}, func(dsp *dispatch.Dispatcher) {
+		dsp.Workers = s.wks
+		dsp.Commit = func(art *schema.Article) {
+			mut.Lock()
+			defer mut.Unlock()
+
+			// every article of the partition up to the committed one is handled
+			for off := int64(0); off <= *art.Event.Offset; off++ {
+				s.Assert().True(hds[*art.Event.Partition][off])
+			}
+
+			cms[*art.Event.Partition] = append(cms[*art.Event.Partition], *art.Event.Offset)
+		}
+	})
+
+	for i := 0; i < s.ats*s.vrs; i++ {
+		art := newArticle("enwiki", i, 0)
+		ptn := i % 2
+		off := int64(i / 2)
+		art.Event = &schema.Event{Partition: &ptn, Offset: &off}
+
+		s.Assert().NoError(dsp.Dispatch(art))
+	}
+
+	s.Assert().NoError(dsp.Dispatch(newArticle("enwiki", 0, 0)))
+	s.Assert().NoError(dsp.Close())
+	s.Assert().Len(cms, 2)
+
+	for _, ofs := range cms {
+		s.Assert().IsIncreasing(ofs)
+		s.Assert().Equal(int64(s.ats*s.vrs/2-1), ofs[len(ofs)-1])
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	for _, testCase := range []*dispatchTestSuite{
+		{
+			wks: 1,
+			ats: 10,
+			vrs: 5,
+		},
+		{
+			wks: 8,
+			ats: 50,
+			vrs: 10,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,243 @@
+// Package filter holds the filter definition shared by the API and realtime clients,
+// and evaluates the filters locally, so the same definitions work for the API, realtime and offline files.
+package filter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Filter represents a filter to be applied to a dataset.
+type Filter struct {
+	// Field specifies the field in the dataset that the filter should be applied to.
+	// Nested fields are separated by dots, for example `is_part_of.identifier` or `event.type`.
+	Field string `json:"field"`
+
+	// Value specifies the value that the field should be compared to.
+	Value interface{} `json:"value"`
+}
+
+// Match checks if the value (for example *schema.Article or *schema.Thing) matches the filter.
+// Fields are looked up by their `json` names, if the path goes through a list, any of the elements can match.
+// Numbers are compared by value regardless of the type, other values by their JSON representation.
+func (f *Filter) Match(val interface{}) bool {
+	exp := normalize(reflect.ValueOf(f.Value))
+
+	for _, fvl := range lookup(reflect.ValueOf(val), strings.Split(f.Field, "."), nil) {
+		if normalize(fvl) == exp {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match checks if the value matches all of the filters, nil filters are ignored.
+func Match(val interface{}, fts ...*Filter) bool {
+	for _, ftr := range fts {
+		if ftr != nil && !ftr.Match(val) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Fields returns the list of fields used by the filters.
+func Fields(fts ...*Filter) []string {
+	fds := []string{}
+
+	for _, ftr := range fts {
+		if ftr != nil {
+			fds = append(fds, ftr.Field)
+		}
+	}
+
+	return fds
+}
+
+// FieldType returns the type of the field on the path (pointers and lists are unwrapped), false if there's no such field.
+// For example for schema.Article, `namespace.identifier` is int and `categories.name` is string.
+func FieldType(typ reflect.Type, fld string) (reflect.Type, bool) {
+	for _, key := range strings.Split(fld, ".") {
+		typ = elemType(typ)
+
+		if typ.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		idx, ok := fieldIndex(typ)[key]
+
+		if !ok {
+			return nil, false
+		}
+
+		typ = typ.FieldByIndex(idx).Type
+	}
+
+	return elemType(typ), true
+}
+
+func elemType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
+
+// lookup collects all of the values on the path.
+func lookup(val reflect.Value, pth []string, vls []reflect.Value) []reflect.Value {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return vls
+		}
+
+		val = val.Elem()
+	}
+
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		for i := 0; i < val.Len(); i++ {
+			vls = lookup(val.Index(i), pth, vls)
+		}
+
+		return vls
+	}
+
+	if len(pth) == 0 {
+		return append(vls, val)
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		idx, ok := fieldIndex(val.Type())[pth[0]]
+
+		if !ok {
+			return vls
+		}
+
+		fvl, err := val.FieldByIndexErr(idx)
+
+		if err != nil {
+			return vls
+		}
+
+		return lookup(fvl, pth[1:], vls)
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return vls
+		}
+
+		if mvl := val.MapIndex(reflect.ValueOf(pth[0]).Convert(val.Type().Key())); mvl.IsValid() {
+			return lookup(mvl, pth[1:], vls)
+		}
+	}
+
+	return vls
+}
+
+// normalize converts the value into a comparable form.
+func normalize(val reflect.Value) interface{} {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.String:
+		return val.String()
+	case reflect.Bool:
+		return val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		return val.Float()
+	}
+
+	// things like time.Time are compared by their JSON representation
+	dta, err := json.Marshal(val.Interface())
+
+	if err != nil {
+		return nil
+	}
+
+	var nvl interface{}
+
+	if err := json.Unmarshal(dta, &nvl); err != nil {
+		return nil
+	}
+
+	switch nvl.(type) {
+	case string, bool, float64, nil:
+		return nvl
+	}
+
+	return string(dta)
+}
+
+var fieldIndexes sync.Map
+
+// fieldIndex maps the `json` names of the struct fields to their indexes, results are cached per type.
+func fieldIndex(typ reflect.Type) map[string][]int {
+	if fix, ok := fieldIndexes.Load(typ); ok {
+		return fix.(map[string][]int)
+	}
+
+	fix := map[string][]int{}
+	ebd := map[string][]int{}
+
+	for i := 0; i < typ.NumField(); i++ {
+		fld := typ.Field(i)
+		tag := fld.Tag.Get("json")
+		nme := strings.Split(tag, ",")[0]
+
+		if nme == "-" {
+			continue
+		}
+
+		// fields of embedded structs are promoted, but have lower priority
+		if fld.Anonymous && len(nme) == 0 {
+			ftp := fld.Type
+
+			if ftp.Kind() == reflect.Ptr {
+				ftp = ftp.Elem()
+			}
+
+			if ftp.Kind() == reflect.Struct {
+				for enm, eix := range fieldIndex(ftp) {
+					ebd[enm] = append([]int{i}, eix...)
+				}
+
+				continue
+			}
+		}
+
+		if !fld.IsExported() {
+			continue
+		}
+
+		if len(nme) == 0 {
+			nme = fld.Name
+		}
+
+		fix[nme] = []int{i}
+	}
+
+	for enm, eix := range ebd {
+		if _, ok := fix[enm]; !ok {
+			fix[enm] = eix
+		}
+	}
+
+	fieldIndexes.Store(typ, fix)
+	return fix
+}
@@ -0,0 +1,159 @@
+package filter_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/filter"
+	"github.com/protsack-stephan/wme/schema/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type filterTestSuite struct {
+	suite.Suite
+	val interface{}
+	fts []*filter.Filter
+	mtc bool
+}
+
+func (s *filterTestSuite) TestMatch() {
+	s.Assert().Equal(s.mtc, filter.Match(s.val, s.fts...))
+}
+
+func TestFilter(t *testing.T) {
+	dmd := time.Date(2023, 2, 28, 10, 0, 0, 0, time.UTC)
+	art := &schema.Article{
+		Name:         "Earth",
+		Identifier:   9228,
+		DateModified: &dmd,
+		IsPartOf:     &schema.Project{Identifier: "enwiki"},
+		Namespace:    &schema.Namespace{Identifier: 0},
+		Event:        &schema.Event{Type: "update"},
+		Categories: []*schema.Category{
+			{Name: "Category:Earth"},
+			{Name: "Category:Planets"},
+		},
+		Version: &schema.Version{
+			Tags: []string{"mobile edit"},
+		},
+	}
+
+	// filters coming from JSON have float64 values
+	fts := []*filter.Filter{}
+	_ = json.Unmarshal([]byte(`[{"field":"namespace.identifier","value":0},{"field":"identifier","value":9228}]`), &fts)
+
+	for _, testCase := range []*filterTestSuite{
+		{
+			val: art,
+			mtc: true,
+		},
+		{
+			val: art,
+			fts: []*filter.Filter{
+				{Field: "is_part_of.identifier", Value: "enwiki"},
+				{Field: "event.type", Value: "update"},
+			},
+			mtc: true,
+		},
+		{
+			val: art,
+			fts: []*filter.Filter{
+				{Field: "is_part_of.identifier", Value: "enwiki"},
+				{Field: "event.type", Value: "delete"},
+			},
+		},
+		{
+			val: art,
+			fts: fts,
+			mtc: true,
+		},
+		{
+			val: art,
+			fts: []*filter.Filter{
+				{Field: "categories.name", Value: "Category:Planets"},
+				{Field: "version.tags", Value: "mobile edit"},
+			},
+			mtc: true,
+		},
+		{
+			val: art,
+			fts: []*filter.Filter{
+				{Field: "date_modified", Value: "2023-02-28T10:00:00Z"},
+			},
+			mtc: true,
+		},
+		{
+			val: art,
+			fts: []*filter.Filter{
+				{Field: "in_language.identifier", Value: "en"},
+			},
+		},
+		{
+			val: art,
+			fts: []*filter.Filter{
+				{Field: "unknown", Value: "enwiki"},
+			},
+		},
+		{
+			val: &schema.Thing{
+				Name:     "Q2",
+				IsPartOf: &schema.Project{Identifier: "wikidatawiki"},
+			},
+			fts: []*filter.Filter{
+				{Field: "is_part_of.identifier", Value: "wikidatawiki"},
+			},
+			mtc: true,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type fieldTypeTestSuite struct {
+	suite.Suite
+	fld string
+	typ reflect.Type
+	ok  bool
+}
+
+func (s *fieldTypeTestSuite) TestFieldType() {
+	typ, ok := filter.FieldType(reflect.TypeOf(schema.Article{}), s.fld)
+
+	s.Assert().Equal(s.ok, ok)
+	s.Assert().Equal(s.typ, typ)
+}
+
+func TestFieldType(t *testing.T) {
+	for _, testCase := range []*fieldTypeTestSuite{
+		{
+			fld: "namespace.identifier",
+			typ: reflect.TypeOf(0),
+			ok:  true,
+		},
+		{
+			fld: "categories.name",
+			typ: reflect.TypeOf(""),
+			ok:  true,
+		},
+		{
+			fld: "date_modified",
+			typ: reflect.TypeOf(time.Time{}),
+			ok:  true,
+		},
+		{
+			fld: "event",
+			typ: reflect.TypeOf(schema.Event{}),
+			ok:  true,
+		},
+		{
+			fld: "in_language.identifer",
+		},
+		{
+			fld: "name.identifier",
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/protsack-stephan/wme/pkg/idle"
 	"github.com/protsack-stephan/wme/schema/v1"
 )
 
@@ -32,7 +34,9 @@ type Event struct {
 type Client struct {
 	BaseURL     string
 	HTTPClient  *http.Client
+	IdleTimeout time.Duration // Closes the stream if a read waits for the data (including keepalive) for the duration, the error is *idle.TimeoutError, disabled if 0. Reconnecting is left to the caller.
 	accessToken string
+	tokenSource auth.TokenGetter
 }
 
 // NewClient create new realtime client.
@@ -53,7 +57,13 @@ func (c *Client) subscribe(ctx context.Context, since time.Time, url string, cb
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Accept", "text/event-stream")
 	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+	tkn, err := c.getToken(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tkn))
 	res, err := c.HTTPClient.Do(req)
 
 	if err != nil {
@@ -63,16 +73,13 @@ func (c *Client) subscribe(ctx context.Context, since time.Time, url string, cb
 	defer res.Body.Close()
 
 	if res.StatusCode < http.StatusOK || res.StatusCode > http.StatusIMUsed {
-		data, err := io.ReadAll(res.Body)
-
-		if err != nil {
-			return err
-		}
-
-		return fmt.Errorf("%s: %s", res.Status, string(data))
+		return apierror.New(res)
 	}
 
-	scn := bufio.NewScanner(res.Body)
+	bdy := idle.NewReader(res.Body, c.IdleTimeout)
+	defer bdy.Close()
+
+	scn := bufio.NewScanner(bdy)
 	buf := []byte{}
 	scn.Buffer(buf, 20971520) // this is important as we are encountering large messages (approx 20MB)
 
@@ -99,7 +106,7 @@ func (c *Client) subscribe(ctx context.Context, since time.Time, url string, cb
 		}
 	}
 
-	return nil
+	return scn.Err()
 }
 
 // SetAccessToken sets access token for authentication.
@@ -112,6 +119,19 @@ func (c *Client) GetAccessToken() string {
 	return c.accessToken
 }
 
+// SetTokenSource sets the source of access tokens, takes precedence over the access token.
+func (c *Client) SetTokenSource(tokenSource auth.TokenGetter) {
+	c.tokenSource = tokenSource
+}
+
+func (c *Client) getToken(ctx context.Context) (string, error) {
+	if c.tokenSource != nil {
+		return c.tokenSource.GetToken(ctx)
+	}
+
+	return c.accessToken, nil
+}
+
 // PageUpdate opens connection to page update stream.
 func (c *Client) PageUpdate(ctx context.Context, since time.Time, cb func(evt *Event)) error {
 	return c.subscribe(ctx, since, "/page-update", cb)
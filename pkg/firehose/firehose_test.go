@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/protsack-stephan/wme/pkg/firehose"
+	"github.com/protsack-stephan/wme/pkg/idle"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -24,6 +25,8 @@ type firehoseClientTestSuite struct {
 	data  []string
 	sts   int
 	err   error
+	stl   bool
+	tmt   time.Duration
 	srv   *httptest.Server
 }
 
@@ -48,6 +51,12 @@ func (s *firehoseClientTestSuite) SetupSuite() {
 			c.Writer.Flush()
 		}
 
+		// keeps the connection open without sending anything
+		if s.stl {
+			<-c.Request.Context().Done()
+			return
+		}
+
 		c.Status(http.StatusOK)
 	}
 
@@ -60,6 +69,7 @@ func (s *firehoseClientTestSuite) SetupSuite() {
 	s.srv = httptest.NewServer(rtr)
 	s.cl = firehose.NewClient()
 	s.cl.BaseURL = s.srv.URL
+	s.cl.IdleTimeout = s.tmt
 }
 
 func (s *firehoseClientTestSuite) assertEvents(evs []*firehose.Event) {
@@ -91,6 +101,10 @@ func (s *firehoseClientTestSuite) TestPageUpdate() {
 		s.Assert().Contains(err.Error(), fmt.Sprint(s.sts))
 	}
 
+	if s.stl {
+		s.Assert().True(idle.IsTimeout(err))
+	}
+
 	s.assertEvents(evs)
 }
 
@@ -106,6 +120,10 @@ func (s *firehoseClientTestSuite) TestPageDelete() {
 		s.Assert().Contains(err.Error(), fmt.Sprint(s.sts))
 	}
 
+	if s.stl {
+		s.Assert().True(idle.IsTimeout(err))
+	}
+
 	s.assertEvents(evs)
 }
 
@@ -121,6 +139,10 @@ func (s *firehoseClientTestSuite) TestPageVisibility() {
 		s.Assert().Contains(err.Error(), fmt.Sprint(s.sts))
 	}
 
+	if s.stl {
+		s.Assert().True(idle.IsTimeout(err))
+	}
+
 	s.assertEvents(evs)
 }
 
@@ -139,6 +161,17 @@ func TestFirehoseClient(t *testing.T) {
 			},
 			since: time.Now().Add(-1 * time.Hour),
 		},
+		{
+			ids: []string{
+				`[{"topic":"aws.data-service.page-update.3","partition":0,"dt":"2022-07-24T13:03:10.431Z","timestamp":1658667790431,"offset":912025743}]`,
+			},
+			data: []string{
+				`{"name":"Ninja","identifier":1290049,"date_modified":"2022-03-19T16:25:42Z"}`,
+			},
+			since: time.Now().Add(-1 * time.Hour),
+			stl:   true,
+			tmt:   time.Millisecond * 50,
+		},
 		{
 			sts: http.StatusInternalServerError,
 			err: errors.New(http.StatusText(http.StatusInternalServerError)),
@@ -147,3 +180,73 @@ func TestFirehoseClient(t *testing.T) {
 		suite.Run(t, testCase)
 	}
 }
+
+type tokenGetter struct {
+	tkn string
+	err error
+}
+
+func (t *tokenGetter) GetToken(_ context.Context) (string, error) {
+	return t.tkn, t.err
+}
+
+type firehoseTokenSourceTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	cl  *firehose.Client
+	act string
+	tks *tokenGetter
+	hdr string
+	err error
+}
+
+func (s *firehoseTokenSourceTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	rtr := gin.New()
+
+	s.hdr = ""
+	rtr.GET("/page-update", func(c *gin.Context) {
+		s.hdr = c.GetHeader("Authorization")
+		c.Status(http.StatusOK)
+	})
+
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(rtr)
+	s.cl = firehose.NewClient()
+	s.cl.BaseURL = s.srv.URL
+	s.cl.SetAccessToken(s.act)
+	s.cl.SetTokenSource(s.tks)
+}
+
+func (s *firehoseTokenSourceTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *firehoseTokenSourceTestSuite) TestPageUpdate() {
+	err := s.cl.PageUpdate(s.ctx, time.Now(), func(evt *firehose.Event) {})
+
+	if s.err != nil {
+		s.Assert().Equal(s.err, err)
+		s.Assert().Empty(s.hdr)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(fmt.Sprintf("Bearer %s", s.tks.tkn), s.hdr)
+}
+
+func TestFirehoseTokenSource(t *testing.T) {
+	for _, testCase := range []*firehoseTokenSourceTestSuite{
+		{
+			act: "access_token",
+			tks: &tokenGetter{tkn: "source_token"},
+		},
+		{
+			tks: &tokenGetter{err: errors.New("login failed")},
+			err: errors.New("login failed"),
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
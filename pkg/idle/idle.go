@@ -0,0 +1,113 @@
+// Package idle detects streaming connections that silently stalled, so they can be closed and reopened
+// instead of blocking forever on the read.
+package idle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// TimeoutError is returned when nothing (including keepalive messages) was received for the timeout.
+// It implements net.Error and is temporary, so the retry policies treat it as a connection failure.
+type TimeoutError struct {
+	Duration time.Duration // Idle timeout that expired.
+}
+
+// Error returns the error message.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("idle: no data received for %s", e.Duration)
+}
+
+// Timeout is always true, part of net.Error interface.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary is always true, the connection can be reopened.
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// IsTimeout checks if the error is caused by the idle timeout.
+func IsTimeout(err error) bool {
+	ter := new(TimeoutError)
+	return errors.As(err, &ter)
+}
+
+// NewReader wraps the connection body, the body is closed if a read waits for the data longer than the timeout.
+// Timeout of 0 or less disables the detection.
+func NewReader(rdr io.ReadCloser, tmt time.Duration) *Reader {
+	ird := &Reader{
+		rdr: rdr,
+		tmt: tmt,
+	}
+
+	// the timer runs only while the read is blocked
+	if tmt > 0 {
+		ird.tmr = time.AfterFunc(tmt, ird.expire)
+		ird.tmr.Stop()
+	}
+
+	return ird
+}
+
+// Reader closes the underlying reader once a read is blocked for too long, which unblocks it.
+// Time between the reads (for example a slow callback) is not counted as idle.
+type Reader struct {
+	rdr io.ReadCloser
+	tmt time.Duration
+	tmr *time.Timer
+	mut sync.Mutex
+	exp bool
+}
+
+// Read reads from the underlying reader, the timer is started for the duration of the call.
+// Returns *TimeoutError once the timeout expired.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mut.Lock()
+
+	if r.exp {
+		r.mut.Unlock()
+		return 0, &TimeoutError{Duration: r.tmt}
+	}
+
+	if r.tmr != nil {
+		r.tmr.Reset(r.tmt)
+	}
+
+	r.mut.Unlock()
+	n, err := r.rdr.Read(p)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.tmr != nil {
+		r.tmr.Stop()
+	}
+
+	if r.exp {
+		return n, &TimeoutError{Duration: r.tmt}
+	}
+
+	return n, err
+}
+
+// Close stops the timer and closes the underlying reader.
+func (r *Reader) Close() error {
+	if r.tmr != nil {
+		r.tmr.Stop()
+	}
+
+	return r.rdr.Close()
+}
+
+func (r *Reader) expire() {
+	r.mut.Lock()
+	r.exp = true
+	r.mut.Unlock()
+
+	_ = r.rdr.Close()
+}
@@ -0,0 +1,110 @@
+package idle_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/idle"
+	"github.com/stretchr/testify/suite"
+)
+
+type idleTestSuite struct {
+	suite.Suite
+	tmt time.Duration
+	itv time.Duration
+	slp time.Duration
+	wts int
+	exp bool
+}
+
+// readAll reads until EOF like io.ReadAll, but sleeps between the reads like a slow callback would.
+func (s *idleTestSuite) readAll(rdr io.Reader) ([]byte, error) {
+	dta := []byte{}
+	buf := make([]byte, 512)
+
+	for {
+		n, err := rdr.Read(buf)
+		dta = append(dta, buf[:n]...)
+
+		if err == io.EOF {
+			return dta, nil
+		}
+
+		if err != nil {
+			return dta, err
+		}
+
+		time.Sleep(s.slp)
+	}
+}
+
+func (s *idleTestSuite) TestRead() {
+	prd, pwr := io.Pipe()
+	ird := idle.NewReader(prd, s.tmt)
+	defer ird.Close()
+
+	// writes with the interval and then stalls without closing the connection
+	go func() {
+		for i := 0; i < s.wts; i++ {
+			time.Sleep(s.itv)
+
+			if _, err := pwr.Write([]byte("keepalive\n")); err != nil {
+				return
+			}
+		}
+
+		if !s.exp {
+			_ = pwr.Close()
+		}
+	}()
+
+	dta, err := s.readAll(ird)
+
+	if !s.exp {
+		s.Assert().NoError(err)
+		s.Assert().Len(dta, s.wts*len("keepalive\n"))
+		return
+	}
+
+	s.Assert().Len(dta, s.wts*len("keepalive\n"))
+	s.Assert().True(idle.IsTimeout(err))
+	s.Assert().EqualError(err, "idle: no data received for "+s.tmt.String())
+
+	ner := net.Error(nil)
+	s.Assert().True(errors.As(err, &ner))
+	s.Assert().True(ner.Timeout())
+}
+
+func TestIdle(t *testing.T) {
+	for _, testCase := range []*idleTestSuite{
+		{
+			tmt: time.Millisecond * 50,
+			itv: time.Millisecond * 10,
+			wts: 10,
+			exp: true,
+		},
+		{
+			tmt: time.Millisecond * 50,
+			itv: time.Millisecond * 10,
+			wts: 10,
+		},
+		{
+			tmt: time.Millisecond * 20,
+			exp: true,
+		},
+		{
+			itv: time.Millisecond * 30,
+			wts: 2,
+		},
+		{
+			tmt: time.Millisecond * 20,
+			slp: time.Millisecond * 50,
+			wts: 3,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,176 @@
+// Package ndjson holds a newline delimited JSON line reader with a configurable line size limit
+// and the decode errors shared by the clients.
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxLineSize is the line size limit used when none is set, large articles can be around 20MB.
+const DefaultMaxLineSize = 20971520
+
+// MaxRawSize is the maximum number of bytes of the failed line kept in DecodeError.
+const MaxRawSize = 1024
+
+// ErrLineTooLong is returned when the line exceeds the maximum size, the line is skipped so reading can continue.
+var ErrLineTooLong = errors.New("ndjson: line exceeds the maximum size")
+
+// DecodeError describes a line that can't be read or decoded.
+type DecodeError struct {
+	Entry  string // Name of the archive entry the line belongs to, empty for streams.
+	Line   int    // Line number, starting from 1.
+	Offset int64  // Byte offset of the start of the line within the entry (or stream).
+	Raw    []byte // Beginning of the line, truncated to MaxRawSize bytes.
+	Err    error  // Underlying error, ErrLineTooLong or JSON syntax error.
+}
+
+// NewDecodeError creates a decode error for the line, keeping a copy of at most MaxRawSize bytes of it.
+func NewDecodeError(ent string, lne int, off int64, raw []byte, err error) *DecodeError {
+	if len(raw) > MaxRawSize {
+		raw = raw[:MaxRawSize]
+	}
+
+	return &DecodeError{
+		Entry:  ent,
+		Line:   lne,
+		Offset: off,
+		Raw:    append([]byte(nil), raw...),
+		Err:    err,
+	}
+}
+
+// Error returns the description of the failed line.
+func (e *DecodeError) Error() string {
+	if len(e.Entry) > 0 {
+		return fmt.Sprintf("can't decode line %d of '%s': %v", e.Line, e.Entry, e.Err)
+	}
+
+	return fmt.Sprintf("can't decode line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrorHandler decides what to do with a line that can't be read or decoded.
+// Return nil to skip the line and continue, or an error to stop the reading.
+type DecodeErrorHandler func(err *DecodeError) error
+
+// Handle calls the handler, if there's no handler the error is returned as is (reading stops).
+func (h DecodeErrorHandler) Handle(err *DecodeError) error {
+	if h == nil {
+		return err
+	}
+
+	return h(err)
+}
+
+// NewReader creates a line reader with mls maximum line size, DefaultMaxLineSize is used if mls is not positive.
+func NewReader(rdr io.Reader, mls int) *Reader {
+	if mls <= 0 {
+		mls = DefaultMaxLineSize
+	}
+
+	return &Reader{
+		brd: bufio.NewReaderSize(rdr, 65536),
+		mls: mls,
+	}
+}
+
+// Reader reads newline delimited lines, empty lines are skipped.
+// Unlike bufio.Scanner it doesn't give up on lines that are too long, but skips them.
+type Reader struct {
+	brd *bufio.Reader
+	mls int
+	lne int
+	off int64
+	nxt int64
+	buf []byte
+}
+
+// ReadLine returns the next non empty line without the line ending, or io.EOF at the end of the input.
+// For lines that are too long the beginning of the line is returned together with ErrLineTooLong.
+// Returned slice is only valid until the next call.
+func (r *Reader) ReadLine() ([]byte, error) {
+	for {
+		lne, err := r.readLine()
+
+		if err != nil {
+			return lne, err
+		}
+
+		if len(lne) > 0 {
+			return lne, nil
+		}
+	}
+}
+
+// Line returns the number of the last line read, starting from 1.
+func (r *Reader) Line() int {
+	return r.lne
+}
+
+// Offset returns the byte offset of the start of the last line read.
+func (r *Reader) Offset() int64 {
+	return r.off
+}
+
+func (r *Reader) readLine() ([]byte, error) {
+	r.buf = r.buf[:0]
+	r.off = r.nxt
+	red := 0
+	tln := false
+
+	for {
+		frg, err := r.brd.ReadSlice('\n')
+		red += len(frg)
+
+		// stop buffering once the line is too long (allowing for `\r\n`), the rest of it is discarded
+		if !tln {
+			if lft := r.mls + 2 - len(r.buf); len(frg) > lft {
+				tln = true
+				r.buf = append(r.buf, frg[:lft]...)
+			} else {
+				r.buf = append(r.buf, frg...)
+			}
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err == io.EOF {
+			if red == 0 {
+				return nil, io.EOF
+			}
+
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		break
+	}
+
+	r.lne++
+	r.nxt += int64(red)
+
+	if tln {
+		return r.buf, ErrLineTooLong
+	}
+
+	lne := bytes.TrimSuffix(bytes.TrimSuffix(r.buf, []byte("\n")), []byte("\r"))
+
+	if len(lne) > r.mls {
+		return lne, ErrLineTooLong
+	}
+
+	return lne, nil
+}
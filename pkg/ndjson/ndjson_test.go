@@ -0,0 +1,116 @@
+package ndjson_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/protsack-stephan/wme/pkg/ndjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type line struct {
+	num int
+	off int64
+	dta string
+	err error
+}
+
+type readerTestSuite struct {
+	suite.Suite
+	inp string
+	mls int
+	lns []line
+}
+
+func (s *readerTestSuite) TestReadLine() {
+	lrd := ndjson.NewReader(strings.NewReader(s.inp), s.mls)
+	lns := []line{}
+
+	for {
+		lne, err := lrd.ReadLine()
+
+		if err == io.EOF {
+			break
+		}
+
+		lns = append(lns, line{num: lrd.Line(), off: lrd.Offset(), dta: string(lne), err: err})
+	}
+
+	s.Assert().Equal(s.lns, lns)
+}
+
+func TestReader(t *testing.T) {
+	lng := strings.Repeat("a", 100000)
+
+	for _, testCase := range []*readerTestSuite{
+		{
+			inp: "{\"name\":\"Earth\"}\n{\"name\":\"Mars\"}\n",
+			lns: []line{
+				{num: 1, dta: `{"name":"Earth"}`},
+				{num: 2, off: 17, dta: `{"name":"Mars"}`},
+			},
+		},
+		{
+			inp: "{\"name\":\"Earth\"}\r\n\n{\"name\":\"Mars\"}",
+			lns: []line{
+				{num: 1, dta: `{"name":"Earth"}`},
+				{num: 3, off: 19, dta: `{"name":"Mars"}`},
+			},
+		},
+		{
+			inp: "short\n" + lng + "\nshort\n",
+			mls: 10,
+			lns: []line{
+				{num: 1, dta: "short"},
+				{num: 2, off: 6, dta: lng[:12], err: ndjson.ErrLineTooLong},
+				{num: 3, off: 100007, dta: "short"},
+			},
+		},
+		{
+			inp: "short\n" + lng,
+			mls: 100000,
+			lns: []line{
+				{num: 1, dta: "short"},
+				{num: 2, off: 6, dta: lng},
+			},
+		},
+		{
+			inp: "",
+			lns: []line{},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+func TestDecodeErrorHandler(t *testing.T) {
+	dre := &ndjson.DecodeError{Entry: "enwiki_namespace_0_0.ndjson", Line: 2, Err: ndjson.ErrLineTooLong}
+
+	// nil handler stops the reading
+	var hdr ndjson.DecodeErrorHandler
+	assert.ErrorIs(t, hdr.Handle(dre), ndjson.ErrLineTooLong)
+
+	hdr = func(err *ndjson.DecodeError) error {
+		return nil
+	}
+	assert.NoError(t, hdr.Handle(dre))
+}
+
+func TestNewDecodeError(t *testing.T) {
+	lrd := ndjson.NewReader(strings.NewReader("{}\n"+strings.Repeat("a", 2000)+"\n"), 100)
+
+	_, err := lrd.ReadLine()
+	assert.NoError(t, err)
+
+	raw, err := lrd.ReadLine()
+	assert.ErrorIs(t, err, ndjson.ErrLineTooLong)
+
+	dre := ndjson.NewDecodeError("enwiki_namespace_0_0.ndjson", lrd.Line(), lrd.Offset(), raw, err)
+	assert.Equal(t, "enwiki_namespace_0_0.ndjson", dre.Entry)
+	assert.Equal(t, 2, dre.Line)
+	assert.Equal(t, int64(3), dre.Offset)
+	assert.LessOrEqual(t, len(dre.Raw), ndjson.MaxRawSize)
+	assert.ErrorIs(t, dre, ndjson.ErrLineTooLong)
+}
@@ -6,9 +6,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"sync"
 
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/protsack-stephan/wme/pkg/ratelimit"
 	"github.com/protsack-stephan/wme/schema/v1"
 )
 
@@ -22,7 +25,11 @@ type ArticleRequest struct {
 type Client struct {
 	BaseURL     string
 	HTTPClient  *http.Client
+	Limiter     *ratelimit.Limiter // Client side rate limiter shared by all lookups, no limit if nil.
 	accessToken string
+	tokenSource auth.TokenGetter
+	mut         sync.Mutex
+	quota       *ratelimit.Quota
 }
 
 // NewClient creates new on-demand client.
@@ -40,6 +47,10 @@ func (c *Client) get(ctx context.Context, url string, v interface{}) (*http.Resp
 		return nil, err
 	}
 
+	if err := c.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s", c.BaseURL, url), body)
 	if err != nil {
 		return nil, err
@@ -47,27 +58,41 @@ func (c *Client) get(ctx context.Context, url string, v interface{}) (*http.Resp
 
 	req.Header.Set("Content-type", "application/json")
 	req.Header.Set("Cache-Control", "no-cache")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+	tkn, err := c.getToken(ctx)
 
-	res, err := c.HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode < http.StatusOK || res.StatusCode > http.StatusIMUsed {
-		data, err := io.ReadAll(res.Body)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tkn))
 
-		if err != nil {
-			return nil, err
-		}
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if qta := ratelimit.ParseQuota(res.Header); qta != nil {
+		c.mut.Lock()
+		c.quota = qta
+		c.mut.Unlock()
+	}
 
+	if res.StatusCode < http.StatusOK || res.StatusCode > http.StatusIMUsed {
 		defer res.Body.Close()
-		return nil, fmt.Errorf("%s: %s", res.Status, string(data))
+		return nil, apierror.New(res)
 	}
 
 	return res, nil
 }
 
+// GetQuota returns the latest quota usage reported in the response headers, nil if API didn't report one.
+func (c *Client) GetQuota() *ratelimit.Quota {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.quota
+}
+
 // SetAccessToken sets access token for authentication.
 func (c *Client) SetAccessToken(accessToken string) {
 	c.accessToken = accessToken
@@ -78,6 +103,19 @@ func (c *Client) GetAccessToken() string {
 	return c.accessToken
 }
 
+// SetTokenSource sets the source of access tokens, takes precedence over the access token.
+func (c *Client) SetTokenSource(tokenSource auth.TokenGetter) {
+	c.tokenSource = tokenSource
+}
+
+func (c *Client) getToken(ctx context.Context) (string, error) {
+	if c.tokenSource != nil {
+		return c.tokenSource.GetToken(ctx)
+	}
+
+	return c.accessToken, nil
+}
+
 // Article triggers /pages/meta/{project}/{name} endpoint and returns current revision of an article.
 func (c *Client) Article(ctx context.Context, req *ArticleRequest) (*schema.Page, error) {
 	res, err := c.get(ctx, fmt.Sprintf("/pages/meta/%s/%s", req.Project, req.Name), nil)
@@ -194,3 +194,135 @@ func TestOndemandProjects(t *testing.T) {
 		suite.Run(t, testCase)
 	}
 }
+
+type tokenGetter struct {
+	tkn string
+	err error
+}
+
+func (t *tokenGetter) GetToken(_ context.Context) (string, error) {
+	return t.tkn, t.err
+}
+
+type odClientTokenSourceTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	odc *ondemand.Client
+	ctx context.Context
+	act string
+	tks *tokenGetter
+	hdr string
+	err error
+}
+
+func (s *odClientTokenSourceTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	s.hdr = ""
+	rtr := gin.New()
+	rtr.GET("/projects", func(c *gin.Context) {
+		s.hdr = c.GetHeader("Authorization")
+		c.JSON(http.StatusOK, []*schema.Project{})
+	})
+
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(rtr)
+	s.odc = &ondemand.Client{
+		HTTPClient: &http.Client{},
+		BaseURL:    s.srv.URL,
+	}
+	s.odc.SetAccessToken(s.act)
+	s.odc.SetTokenSource(s.tks)
+}
+
+func (s *odClientTokenSourceTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *odClientTokenSourceTestSuite) TestProjects() {
+	_, err := s.odc.Projects(s.ctx)
+
+	if s.err != nil {
+		s.Assert().Equal(s.err, err)
+		s.Assert().Empty(s.hdr)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(fmt.Sprintf("Bearer %s", s.tks.tkn), s.hdr)
+}
+
+func TestOndemandTokenSource(t *testing.T) {
+	for _, testCase := range []*odClientTokenSourceTestSuite{
+		{
+			act: "access_token",
+			tks: &tokenGetter{tkn: "source_token"},
+		},
+		{
+			tks: &tokenGetter{err: errors.New("login failed")},
+			err: errors.New("login failed"),
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type odClientQuotaTestSuite struct {
+	suite.Suite
+	srv *httptest.Server
+	odc *ondemand.Client
+	ctx context.Context
+	lmt string
+	rmn string
+}
+
+func (s *odClientQuotaTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	rtr := gin.New()
+	rtr.GET("/projects", func(c *gin.Context) {
+		if len(s.lmt) > 0 {
+			c.Header("X-RateLimit-Limit", s.lmt)
+			c.Header("X-RateLimit-Remaining", s.rmn)
+		}
+
+		c.JSON(http.StatusOK, []*schema.Project{})
+	})
+
+	s.ctx = context.Background()
+	s.srv = httptest.NewServer(rtr)
+	s.odc = &ondemand.Client{
+		HTTPClient: &http.Client{},
+		BaseURL:    s.srv.URL,
+	}
+}
+
+func (s *odClientQuotaTestSuite) TearDownSuite() {
+	s.srv.Close()
+}
+
+func (s *odClientQuotaTestSuite) TestGetQuota() {
+	_, err := s.odc.Projects(s.ctx)
+	s.Assert().NoError(err)
+
+	if len(s.lmt) == 0 {
+		s.Assert().Nil(s.odc.GetQuota())
+		return
+	}
+
+	s.Assert().NotNil(s.odc.GetQuota())
+	s.Assert().Equal(s.lmt, fmt.Sprint(s.odc.GetQuota().Limit))
+	s.Assert().Equal(s.rmn, fmt.Sprint(s.odc.GetQuota().Remaining))
+}
+
+func TestOndemandQuota(t *testing.T) {
+	for _, testCase := range []*odClientQuotaTestSuite{
+		{
+			lmt: "100",
+			rmn: "99",
+		},
+		{},
+	} {
+		suite.Run(t, testCase)
+	}
+}
@@ -0,0 +1,110 @@
+// Package ratelimit holds a client side token bucket rate limiter
+// to stay within the WME API quota when sending requests from multiple goroutines.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewLimiter creates new token bucket limiter that allows rte requests per second with bursts of up to bst requests.
+func NewLimiter(rte float64, bst int) *Limiter {
+	return &Limiter{
+		rate:   rte,
+		burst:  float64(bst),
+		tokens: float64(bst),
+		last:   time.Now(),
+	}
+}
+
+// Limiter is a token bucket rate limiter, safe for concurrent use.
+// Nil limiter doesn't limit anything.
+type Limiter struct {
+	mut    sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Wait blocks until the request is allowed or context is canceled.
+// Requests are served in the order they called Wait.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return nil
+	}
+
+	l.mut.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	l.last = now
+
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+
+	l.tokens--
+	tks := l.tokens
+	l.mut.Unlock()
+
+	if tks >= 0 {
+		return nil
+	}
+
+	tmr := time.NewTimer(time.Duration(-tks / l.rate * float64(time.Second)))
+	defer tmr.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.mut.Lock()
+		l.tokens++
+		l.mut.Unlock()
+		return ctx.Err()
+	case <-tmr.C:
+		return nil
+	}
+}
+
+// Quota represents the quota usage reported by the API in the response headers.
+type Quota struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset,omitempty"`
+}
+
+// ParseQuota reads quota usage from X-RateLimit-* or RateLimit-* response headers.
+// Returns nil if the headers are not present.
+func ParseQuota(hdr http.Header) *Quota {
+	for _, pfx := range []string{"X-RateLimit-", "RateLimit-"} {
+		lmt, err := strconv.Atoi(strings.TrimSpace(hdr.Get(pfx + "Limit")))
+
+		if err != nil {
+			continue
+		}
+
+		qta := &Quota{
+			Limit: lmt,
+		}
+
+		if rmn, err := strconv.Atoi(strings.TrimSpace(hdr.Get(pfx + "Remaining"))); err == nil {
+			qta.Remaining = rmn
+		}
+
+		if rst, err := strconv.ParseInt(strings.TrimSpace(hdr.Get(pfx+"Reset")), 10, 64); err == nil {
+			// large values are unix timestamps, small ones are seconds left until the reset
+			if rst > 1000000000 {
+				qta.Reset = time.Unix(rst, 0)
+			} else {
+				qta.Reset = time.Now().Add(time.Duration(rst) * time.Second)
+			}
+		}
+
+		return qta
+	}
+
+	return nil
+}
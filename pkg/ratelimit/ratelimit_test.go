@@ -0,0 +1,131 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/wme/pkg/ratelimit"
+	"github.com/stretchr/testify/suite"
+)
+
+type limiterTestSuite struct {
+	suite.Suite
+	rte float64
+	bst int
+	rqs int
+	min time.Duration
+}
+
+func (s *limiterTestSuite) TestWait() {
+	lmt := ratelimit.NewLimiter(s.rte, s.bst)
+	ctx := context.Background()
+	stt := time.Now()
+	wgp := new(sync.WaitGroup)
+
+	for i := 0; i < s.rqs; i++ {
+		wgp.Add(1)
+
+		go func() {
+			defer wgp.Done()
+			s.Assert().NoError(lmt.Wait(ctx))
+		}()
+	}
+
+	wgp.Wait()
+	s.Assert().GreaterOrEqual(time.Since(stt), s.min)
+}
+
+func (s *limiterTestSuite) TestWaitCanceled() {
+	lmt := ratelimit.NewLimiter(s.rte, s.bst)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	for i := 0; i < s.bst; i++ {
+		s.Assert().NoError(lmt.Wait(ctx))
+	}
+
+	cancel()
+	s.Assert().ErrorIs(lmt.Wait(ctx), context.Canceled)
+}
+
+func TestLimiter(t *testing.T) {
+	for _, testCase := range []*limiterTestSuite{
+		{
+			rte: 100,
+			bst: 5,
+			rqs: 5,
+			min: 0,
+		},
+		{
+			rte: 100,
+			bst: 1,
+			rqs: 6,
+			min: time.Millisecond * 45,
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	lmt := (*ratelimit.Limiter)(nil)
+
+	if err := lmt.Wait(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+type quotaTestSuite struct {
+	suite.Suite
+	hdr http.Header
+	qta *ratelimit.Quota
+}
+
+func (s *quotaTestSuite) TestParseQuota() {
+	qta := ratelimit.ParseQuota(s.hdr)
+
+	if s.qta == nil {
+		s.Assert().Nil(qta)
+		return
+	}
+
+	s.Assert().Equal(s.qta.Limit, qta.Limit)
+	s.Assert().Equal(s.qta.Remaining, qta.Remaining)
+	s.Assert().WithinDuration(s.qta.Reset, qta.Reset, time.Second)
+}
+
+func TestQuota(t *testing.T) {
+	for _, testCase := range []*quotaTestSuite{
+		{
+			hdr: http.Header{},
+		},
+		{
+			hdr: http.Header{
+				"X-Ratelimit-Limit":     []string{"100"},
+				"X-Ratelimit-Remaining": []string{"10"},
+				"X-Ratelimit-Reset":     []string{"1700000000"},
+			},
+			qta: &ratelimit.Quota{
+				Limit:     100,
+				Remaining: 10,
+				Reset:     time.Unix(1700000000, 0),
+			},
+		},
+		{
+			hdr: http.Header{
+				"Ratelimit-Limit":     []string{"50"},
+				"Ratelimit-Remaining": []string{"0"},
+				"Ratelimit-Reset":     []string{"60"},
+			},
+			qta: &ratelimit.Quota{
+				Limit:     50,
+				Remaining: 0,
+				Reset:     time.Now().Add(time.Minute),
+			},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
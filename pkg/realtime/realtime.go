@@ -1,8 +1,10 @@
 // Package realtime is a SDK for working with realtime API v2 BETA.
+//
+// Deprecated: api.Client.StreamArticles supports the same parts, offsets and per partition timestamps,
+// and shares the authentication, retries and options with the rest of the API, use it instead.
 package realtime
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -11,38 +13,52 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/protsack-stephan/wme/pkg/apierror"
+	"github.com/protsack-stephan/wme/pkg/auth"
+	"github.com/protsack-stephan/wme/pkg/filter"
+	"github.com/protsack-stephan/wme/pkg/idle"
+	"github.com/protsack-stephan/wme/pkg/ndjson"
 	"github.com/protsack-stephan/wme/schema/v2"
 )
 
 // Filter payload for filters in realtime API.
-type Filter struct {
-	Field string      `json:"field"`
-	Value interface{} `json:"value"`
-}
+// Can be evaluated locally with the filter package.
+type Filter = filter.Filter
 
 // ArticlesRequest request for filtering and fields in realtime API.
+//
+// Deprecated: use api.Request, it has the same fields.
 type ArticlesRequest struct {
 	Since             time.Time         `json:"since,omitempty"`
 	Fields            []string          `json:"fields,omitempty"`
 	Filters           []Filter          `json:"filters,omitempty"`
 	Parts             []int             `json:"parts,omitempty"`
 	Offsets           map[int]int64     `json:"offsets,omitempty"`
-	SincePerPartition map[int]time.Time ` json:"since_per_partition,omitempty"`
+	SincePerPartition map[int]time.Time `json:"since_per_partition,omitempty"`
 }
 
 // NewClient create new realtime client.
+//
+// Deprecated: use api.NewClient and api.Client.StreamArticles instead.
 func NewClient() *Client {
 	return &Client{
-		BaseURL:    "https://realtime-beta.enterprise.wikimedia.com/v2",
-		HTTPClient: &http.Client{},
+		BaseURL:     "https://realtime-beta.enterprise.wikimedia.com/v2",
+		HTTPClient:  &http.Client{},
+		MaxLineSize: ndjson.DefaultMaxLineSize,
 	}
 }
 
 // Client realtime streaming client to simplify work with WME realtime API.
+//
+// Deprecated: use api.Client instead.
 type Client struct {
-	BaseURL     string
-	HTTPClient  *http.Client
-	accessToken string
+	BaseURL            string
+	HTTPClient         *http.Client
+	MaxLineSize        int                       // Maximum size of a single message, longer messages are handled as decode errors.
+	DecodeErrorHandler ndjson.DecodeErrorHandler // Decides whether to skip or stop on messages that can't be decoded, stream stops if nil.
+	IdleTimeout        time.Duration             // Closes the stream if a read waits for the data (including keepalive) for the duration, the error is *idle.TimeoutError, disabled if 0. Reconnecting is left to the caller.
+	accessToken        string
+	tokenSource        auth.TokenGetter
 }
 
 // SetAccessToken sets access token for authentication.
@@ -55,20 +71,35 @@ func (c *Client) GetAccessToken() string {
 	return c.accessToken
 }
 
+// SetTokenSource sets the source of access tokens, takes precedence over the access token.
+func (c *Client) SetTokenSource(tokenSource auth.TokenGetter) {
+	c.tokenSource = tokenSource
+}
+
+func (c *Client) getToken(ctx context.Context) (string, error) {
+	if c.tokenSource != nil {
+		return c.tokenSource.GetToken(ctx)
+	}
+
+	return c.accessToken, nil
+}
+
 // Articles opens and listens articles stream.
 func (cl *Client) Articles(ctx context.Context, req *ArticlesRequest, cb func(art *schema.Article) error) error {
-	return cl.subscribe(ctx, "/articles", req, func(data []byte) error {
+	return cl.subscribe(ctx, "/articles", req, func(data []byte) (func() error, error) {
 		art := new(schema.Article)
 
 		if err := json.Unmarshal(data, art); err != nil {
-			return err
+			return nil, err
 		}
 
-		return cb(art)
+		return func() error { return cb(art) }, nil
 	})
 }
 
-func (c *Client) subscribe(ctx context.Context, url string, body interface{}, cb func(data []byte) error) error {
+// subscribe reads the stream line by line, dcd decodes the line and returns the call of the user callback.
+// Only the errors returned by dcd are decode errors, errors of the user callback are returned as is.
+func (c *Client) subscribe(ctx context.Context, url string, body interface{}, dcd func(data []byte) (func() error, error)) error {
 	bod := bytes.NewBuffer([]byte{})
 
 	if body != nil {
@@ -93,7 +124,13 @@ func (c *Client) subscribe(ctx context.Context, url string, body interface{}, cb
 	req.Header.Set("Accept", "application/x-ndjson")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+	tkn, err := c.getToken(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tkn))
 	res, err := c.HTTPClient.Do(req)
 
 	if err != nil {
@@ -103,23 +140,42 @@ func (c *Client) subscribe(ctx context.Context, url string, body interface{}, cb
 	defer res.Body.Close()
 
 	if res.StatusCode < http.StatusOK || res.StatusCode > http.StatusIMUsed {
-		data, err := io.ReadAll(res.Body)
+		return apierror.New(res)
+	}
 
-		if err != nil {
+	bdy := idle.NewReader(res.Body, c.IdleTimeout)
+	defer bdy.Close()
+
+	// this is important as we are encountering large messages (approx 20MB)
+	lrd := ndjson.NewReader(bdy, c.MaxLineSize)
+
+	for {
+		lne, err := lrd.ReadLine()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil && err != ndjson.ErrLineTooLong {
 			return err
 		}
 
-		return fmt.Errorf("%s: %s", res.Status, string(data))
-	}
+		var cbk func() error
 
-	scn := bufio.NewScanner(res.Body)
-	scn.Buffer([]byte{}, 20971520) // this is important as we are encountering large messages (approx 20MB)
+		if err == nil {
+			cbk, err = dcd(lne)
+		}
+
+		if err != nil {
+			if err := c.DecodeErrorHandler.Handle(ndjson.NewDecodeError("", lrd.Line(), lrd.Offset(), lne, err)); err != nil {
+				return err
+			}
 
-	for scn.Scan() {
-		if err := cb([]byte(scn.Text())); err != nil {
+			continue
+		}
+
+		if err := cbk(); err != nil {
 			return err
 		}
 	}
-
-	return nil
 }
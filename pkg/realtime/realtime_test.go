@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/protsack-stephan/wme/pkg/ndjson"
 	"github.com/protsack-stephan/wme/pkg/realtime"
 	"github.com/protsack-stephan/wme/schema/v2"
 	"github.com/stretchr/testify/suite"
@@ -21,6 +22,9 @@ type realtimeTestSuite struct {
 	srv *httptest.Server
 	cli *realtime.Client
 	dat []string
+	bad []string
+	mls int
+	dre []int
 	err error
 }
 
@@ -29,7 +33,7 @@ func (s *realtimeTestSuite) createServer() http.Handler {
 	rtr := gin.New()
 
 	rtr.POST("/articles", func(gcx *gin.Context) {
-		for _, art := range s.dat {
+		for _, art := range append(s.bad, s.dat...) {
 			fmt.Fprintf(gcx.Writer, "%s\n", art)
 			gcx.Writer.Flush()
 		}
@@ -42,6 +46,11 @@ func (s *realtimeTestSuite) SetupSuite() {
 	s.srv = httptest.NewServer(s.createServer())
 	s.cli = realtime.NewClient()
 	s.cli.BaseURL = s.srv.URL
+	s.cli.MaxLineSize = s.mls
+	s.cli.DecodeErrorHandler = func(err *ndjson.DecodeError) error {
+		s.dre = append(s.dre, err.Line)
+		return nil
+	}
 	s.ctx = context.Background()
 }
 
@@ -65,6 +74,7 @@ func (s *realtimeTestSuite) TestArticles() {
 
 	s.Assert().Equal(s.err, err)
 	s.Assert().Equal(s.dat, dat)
+	s.Assert().Len(s.dre, len(s.bad))
 }
 
 func TestClient(t *testing.T) {
@@ -81,6 +91,93 @@ func TestClient(t *testing.T) {
 				`{"identifier":100}`,
 			},
 		},
+		{
+			dat: []string{
+				`{"name":"Earth"}`,
+			},
+			bad: []string{
+				`{"name":`,
+				`{"name":"Very long article name"}`,
+			},
+			mls: 20,
+		},
+		{
+			dat: []string{
+				`{"name":"Earth"}`,
+			},
+			err: &ndjson.DecodeError{Err: errors.New("callback error")},
+		},
+	} {
+		suite.Run(t, testCase)
+	}
+}
+
+type tokenGetter struct {
+	tkn string
+	err error
+}
+
+func (t *tokenGetter) GetToken(_ context.Context) (string, error) {
+	return t.tkn, t.err
+}
+
+type realtimeTokenSourceTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	srv *httptest.Server
+	cli *realtime.Client
+	act string
+	tks *tokenGetter
+	hdr string
+	err error
+}
+
+func (s *realtimeTokenSourceTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	rtr := gin.New()
+
+	s.hdr = ""
+	rtr.POST("/articles", func(gcx *gin.Context) {
+		s.hdr = gcx.GetHeader("Authorization")
+	})
+
+	s.srv = httptest.NewServer(rtr)
+	s.cli = realtime.NewClient()
+	s.cli.BaseURL = s.srv.URL
+	s.cli.SetAccessToken(s.act)
+	s.cli.SetTokenSource(s.tks)
+	s.ctx = context.Background()
+}
+
+func (s *realtimeTokenSourceTestSuite) TearDownTest() {
+	s.srv.Close()
+}
+
+func (s *realtimeTokenSourceTestSuite) TestArticles() {
+	err := s.cli.Articles(s.ctx, nil, func(art *schema.Article) error {
+		return nil
+	})
+
+	if s.err != nil {
+		s.Assert().Equal(s.err, err)
+		s.Assert().Empty(s.hdr)
+		return
+	}
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(fmt.Sprintf("Bearer %s", s.tks.tkn), s.hdr)
+}
+
+func TestRealtimeTokenSource(t *testing.T) {
+	for _, testCase := range []*realtimeTokenSourceTestSuite{
+		{
+			act: "access_token",
+			tks: &tokenGetter{tkn: "source_token"},
+		},
+		{
+			tks: &tokenGetter{err: errors.New("login failed")},
+			err: errors.New("login failed"),
+		},
 	} {
 		suite.Run(t, testCase)
 	}
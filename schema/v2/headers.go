@@ -9,4 +9,8 @@ type Headers struct {
 	LastModified  *time.Time `json:"last_modified,omitempty"`
 	ContentType   string     `json:"content_type,omitempty"`
 	AcceptRanges  string     `json:"accept_ranges,omitempty"`
+
+	// Checksums published by the API (base64 encoded), empty if the response didn't have them.
+	ContentMD5     string `json:"content_md5,omitempty"`
+	ChecksumSHA256 string `json:"checksum_sha256,omitempty"`
 }